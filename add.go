@@ -0,0 +1,156 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Add adds a file of the kind given to an existing project, rendered from the
+// templates with the license header: a package into the new directory name,
+// or a source, test or example file named by name, into its directory. It
+// returns the file created.
+func (p *Project) Add(kind, name string) (string, error) {
+	if _, ok := ListAdd[kind]; !ok {
+		return "", fmt.Errorf("unavailable kind of file: %q", kind)
+	}
+
+	name = filepath.Clean(name)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".go"), "_test")
+	dir, base := filepath.Dir(name), filepath.Base(name)
+
+	var file, tmplName string
+
+	switch kind {
+	case "pkg":
+		dir = name
+		file, tmplName = filepath.Join(name, base+".go"), "Go"
+	case "file":
+		file, tmplName = name+".go", "Go"
+	case "test":
+		file, tmplName = name+"_test.go", "Test"
+	case "example":
+		file, tmplName = filepath.Join(dir, "example_"+base+"_test.go"), "Example"
+	}
+
+	exist, err := p.fs.Exists(file)
+	if err != nil {
+		return "", err
+	}
+	if exist {
+		return "", &os.PathError{Op: "create", Path: file, Err: os.ErrExist}
+	}
+
+	pkg := base
+	if kind != "pkg" {
+		if pkg, err = packageName(dir); err != nil {
+			return "", err
+		}
+	}
+	pkgPath, err := importPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	p.parseLicense()
+	p.parseProject()
+
+	if p.tmplDir != "" {
+		if _, err = p.parseTemplateDir(); err != nil {
+			return "", err
+		}
+	}
+
+	// The templates use the program name as package name.
+	cfg := *p.cfg
+	cfg.Program = pkg
+	data := *p.data
+	data.Conf = &cfg
+	data.ImportPath, data.ModulePath = pkgPath, pkgPath
+
+	// The functions are named by the file, to can add several ones to a
+	// package; i.e. "TestLru" and "Example_lru" for the name "lru".
+	ident := reNotEnv.ReplaceAllString(base, "_")
+	switch kind {
+	case "test":
+		data.FuncName = strings.ToUpper(ident[:1]) + ident[1:]
+	case "example":
+		data.FuncName = "_" + strings.ToLower(ident[:1]) + ident[1:]
+	}
+
+	oldData := p.data
+	p.data = &data
+	defer func() { p.data = oldData }()
+
+	if err = p.mkdirAll(filepath.Dir(file)); err != nil {
+		return "", err
+	}
+	if err = p.parseFromVar(file, tmplName); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// * * *
+
+// importPath returns the import path of the package into the directory dir,
+// got from the module path of the project.
+func importPath(dir string) (string, error) {
+	root, err := projectRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", err
+	}
+
+	module, err := modulePath(root)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return module, nil
+	}
+	return path.Join(module, filepath.ToSlash(rel)), nil
+}
+
+// packageName returns the name of the package into the directory dir, got from
+// its Go files excepting the external tests; else the name of the directory.
+func packageName(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	fset := token.NewFileSet()
+
+	for _, v := range files {
+		f, err := parser.ParseFile(fset, v, nil, parser.PackageClauseOnly)
+		if err != nil {
+			continue
+		}
+		if name := f.Name.Name; !strings.HasSuffix(name, "_test") {
+			return name, nil
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(reNotEnv.ReplaceAllString(filepath.Base(abs), "_")), nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddKinds(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go tool not found")
+	}
+
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "foo")
+
+	p, err := New(testConf("lib"), &Options{Dir: dir, Output: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Several files of every kind into the same package.
+	for _, name := range []string{"lru", "cache"} {
+		for _, kind := range ListAddSorted {
+			cfg := testConf("")
+			if err = cfg.FromProject(dir); err != nil {
+				t.Fatal(err)
+			}
+			if p, err = New(cfg, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := p.Add(kind, filepath.Join(dir, kind+"_"+name))
+			if err != nil {
+				t.Errorf("kind %s: %s", kind, err)
+				continue
+			}
+			if _, err = os.Stat(file); err != nil {
+				t.Errorf("kind %s: %s", kind, err)
+			}
+		}
+	}
+
+	goVet(t, dir)
+}
@@ -0,0 +1,83 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import "path/filepath"
+
+// CommentStyle represents the way to comment a text in a kind of file.
+type CommentStyle struct {
+	Line  string // prefix of every line
+	Start string // start of a block comment; if empty, it is commented by lines
+	End   string // end of a block comment
+}
+
+var (
+	styleSlash = CommentStyle{Line: "//"}
+	styleHash  = CommentStyle{Line: "#"}
+	styleC     = CommentStyle{Start: "/*", Line: " *", End: " */"}
+	styleHTML  = CommentStyle{Start: "<!--", End: "-->"}
+)
+
+// ListCommentStyle are the comment styles by file extension or, for files
+// without extension, by file name.
+var ListCommentStyle = map[string]CommentStyle{
+	".go":    styleSlash,
+	".proto": styleSlash,
+	".s":     styleSlash, // Go assembler
+
+	// C, and the assembler with C preprocessor, used in cgo
+	".c":   styleC,
+	".h":   styleC,
+	".cc":  styleC,
+	".cpp": styleC,
+	".hpp": styleC,
+	".S":   styleC,
+
+	".asm": {Line: ";"},
+
+	".sh":        styleHash,
+	".bash":      styleHash,
+	".py":        styleHash,
+	".yml":       styleHash,
+	".yaml":      styleHash,
+	".toml":      styleHash,
+	".mk":        styleHash,
+	"Makefile":   styleHash,
+	"Dockerfile": styleHash,
+
+	".sql": {Line: "--"},
+
+	".html": styleHTML,
+	".htm":  styleHTML,
+	".xml":  styleHTML,
+	".md":   styleHTML,
+}
+
+// altCommentStyles are the other comment styles accepted in the files of a
+// comment style, to find the license header; i.e. a block "/* */" in Go.
+var altCommentStyles = map[CommentStyle][]CommentStyle{
+	styleSlash: {styleC},
+}
+
+// commentStyleFor returns the comment style for the file name, and whether it
+// is known.
+func commentStyleFor(name string) (CommentStyle, bool) {
+	key := filepath.Ext(name)
+	if key == "" {
+		key = filepath.Base(name)
+	}
+
+	style, ok := ListCommentStyle[key]
+	return style, ok
+}
+
+// setComment sets the comment style to render the license header.
+func (p *Project) setComment(style CommentStyle) {
+	p.data.Comment = style.Line
+	p.data.CommentStart = style.Start
+	p.data.CommentEnd = style.End
+}
@@ -13,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -24,21 +25,49 @@ import (
 type Conf struct {
 	Project     string
 	Program     string // to lower case
-	License     string
+	License     string // license expression, as "apache OR mit"
+	DocLicense  string // license expression for the documentation, if any
+	SPDX        string // mode to use the SPDX identifier in the header, if any
 	Author      string
 	Email       string
 	VCS         string
+	Kind        string // layout of the project; "lib" by default
 	Org         string // the author develops the program for an organization
 	Import      string // To get data from user configuration; then is sent to ImportPaths
 	ImportPaths []string
+	DataDir     string            `yaml:"data"`      // directory with custom license texts
+	TemplateDir string            `yaml:"templates"` // directory with custom templates
+	Pack        string            // directory with the template pack to use
+	Vars        map[string]string // variables declared by the template pack
+	GoVersion   string            `yaml:"go"` // go directive of go.mod
+	Toolchain   string            // toolchain directive of go.mod, if any
+	Commit      bool              // create the initial commit after of initializing the VCS
+	CommitMsg   string            // message of the initial commit
+}
+
+// Data represents the data passed to templates: the configuration of the
+// project, plus the fields got from it at rendering.
+type Data struct {
+	*Conf
 
-	// To pass to templates
+	Email         string // author and email, as "Jane <jane AT example.com>"
 	ImportPath    string
+	ModulePath    string
+	EnvPrefix     string
+	AuthorsFile   bool
 	Comment       string
+	CommentStart  string
+	CommentEnd    string
 	FullLicense   string
-	GNUextra      string
+	LicenseFile   string
+	Licenses      []*License
+	LicenseOp     string
+	DocLicenses   []*License
+	DocLicenseOp  string
+	SPDXID        string
 	ProjectHeader string
 	Year          int
+	FuncName      string // suffix of the test or example function, at adding it
 }
 
 // SetNames sets names for both project and program.
@@ -67,6 +96,62 @@ func (c *Conf) SetNames() error {
 	return nil
 }
 
+// licenses returns the licenses of the source files, and the operator of the
+// expression.
+func (c *Conf) licenses() ([]*License, string) {
+	list, op, _ := ParseLicense(c.License)
+	return list, op
+}
+
+// docLicenses returns the licenses of the documentation, if any, and the
+// operator of the expression.
+func (c *Conf) docLicenses() ([]*License, string) {
+	if c.DocLicense == "" || strings.ToLower(c.DocLicense) == "none" {
+		return nil, ""
+	}
+	list, op, _ := ParseLicense(c.DocLicense)
+	return list, op
+}
+
+// allLicenses returns the licenses of both source files and documentation,
+// without duplicates and excepting the license "none".
+func (c *Conf) allLicenses() []*License {
+	code, _ := c.licenses()
+	doc, _ := c.docLicenses()
+	all := make([]*License, 0, len(code)+len(doc))
+
+	for _, v := range append(code, doc...) {
+		if strings.ToLower(v.ID) == "none" {
+			continue
+		}
+
+		added := false
+		for _, a := range all {
+			if a == v {
+				added = true
+				break
+			}
+		}
+		if !added {
+			all = append(all, v)
+		}
+	}
+	return all
+}
+
+// checkLicense checks the license expressions.
+func (c *Conf) checkLicense() error {
+	if _, _, err := ParseLicense(c.License); err != nil {
+		return err
+	}
+	if c.DocLicense != "" {
+		if _, _, err := ParseLicense(c.DocLicense); err != nil {
+			return fmt.Errorf("documentation: %s", err)
+		}
+	}
+	return nil
+}
+
 //
 // == User configuration
 
@@ -84,9 +169,13 @@ func (cfg *Conf) AddConfig() error {
 		return err
 	}
 
-	cfg.ImportPath = strings.Join(cfg.ImportPaths, ":")
+	data := &Data{
+		Conf:       cfg,
+		Email:      cfg.Email,
+		ImportPath: strings.Join(cfg.ImportPaths, ":"),
+	}
 
-	if err := tmpl.Execute(file, cfg); err != nil {
+	if err := tmpl.Execute(file, data); err != nil {
 		return fmt.Errorf("execution failed: %s", err)
 	}
 	return nil
@@ -133,9 +222,47 @@ func (c *Conf) UserConfig() error {
 	if c.License == "" && cfg.License != "" {
 		c.License = cfg.License
 	}
+	if c.DocLicense == "" && cfg.DocLicense != "" {
+		c.DocLicense = cfg.DocLicense
+	}
+	if c.SPDX == "" && cfg.SPDX != "" {
+		c.SPDX = cfg.SPDX
+	}
+	if c.Kind == "" && cfg.Kind != "" {
+		c.Kind = cfg.Kind
+	}
 	if c.VCS == "" && cfg.VCS != "" {
 		c.VCS = cfg.VCS
 	}
+	if c.DataDir == "" && cfg.DataDir != "" {
+		c.DataDir = cfg.DataDir
+	}
+	if c.TemplateDir == "" && cfg.TemplateDir != "" {
+		c.TemplateDir = cfg.TemplateDir
+	}
+	if c.Pack == "" && cfg.Pack != "" {
+		c.Pack = cfg.Pack
+	}
+	if c.GoVersion == "" && cfg.GoVersion != "" {
+		c.GoVersion = cfg.GoVersion
+	}
+	if c.Toolchain == "" && cfg.Toolchain != "" {
+		c.Toolchain = cfg.Toolchain
+	}
+	if !c.Commit && cfg.Commit {
+		c.Commit = cfg.Commit
+	}
+	if c.CommitMsg == "" && cfg.CommitMsg != "" {
+		c.CommitMsg = cfg.CommitMsg
+	}
+	for k, v := range cfg.Vars {
+		if _, ok := c.Vars[k]; !ok {
+			if c.Vars == nil {
+				c.Vars = make(map[string]string)
+			}
+			c.Vars[k] = v
+		}
+	}
 	if len(c.ImportPaths) == 0 && cfg.Import != "" {
 		c.ImportPaths = strings.Split(cfg.Import, ":")
 	}
@@ -143,6 +270,59 @@ func (c *Conf) UserConfig() error {
 	return nil
 }
 
+// VCSConfig sets the author and email, if they are not set, from the
+// configuration per user of the VCS: the one set in the field VCS, if any, and
+// then Git, Mercurial and Bazaar (or Breezy). It is meant to be used after of
+// UserConfig, to have the lowest priority.
+func (c *Conf) VCSConfig() error {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return fmt.Errorf("environment variable $HOME is not set")
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	list := []string{"git", "hg", "bzr"}
+	if _, ok := listUserConfigVCS[c.VCS]; ok {
+		list = append([]string{c.VCS}, list...)
+	}
+
+	for _, vcs := range list {
+		if c.Author != "" && c.Email != "" {
+			break
+		}
+
+		for _, name := range listUserConfigVCS[vcs] {
+			file := filepath.Join(home, name)
+			if strings.HasPrefix(name, ".config/") {
+				file = filepath.Join(configHome, strings.TrimPrefix(name, ".config/"))
+			}
+
+			conf, err := readConfig(file)
+			if err != nil {
+				return fmt.Errorf("error parsing configuration of %s: %s", ListVCS[vcs], err)
+			}
+
+			author, email, _ := configVCS(vcs, conf)
+			if author == "" && email == "" {
+				continue
+			}
+
+			if c.Author == "" {
+				c.Author = author
+			}
+			if c.Email == "" {
+				c.Email = email
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
 // == Checking
 //
 
@@ -178,11 +358,15 @@ func (c *Conf) PreCheck(interactive, addConfig bool) error {
 	if c.License != "" {
 		c.License = strings.ToLower(c.License)
 
-		if _, ok := ListLowerLicense[c.License]; !ok {
-			return fmt.Errorf("unavailable license: %q", c.License)
+		if err := c.checkLicense(); err != nil {
+			return err
 		}
 	}
 
+	if err := c.checkSPDX(); err != nil {
+		return err
+	}
+
 	// VCS
 	if c.VCS != "" {
 		c.VCS = strings.ToLower(c.VCS)
@@ -192,9 +376,81 @@ func (c *Conf) PreCheck(interactive, addConfig bool) error {
 		}
 	}
 
+	if err := c.checkKind(); err != nil {
+		return err
+	}
+	return c.checkGoMod()
+}
+
+// checkKind checks the kind of project layout, if any.
+func (c *Conf) checkKind() error {
+	if c.Kind == "" {
+		return nil
+	}
+	c.Kind = strings.ToLower(c.Kind)
+
+	if _, ok := ListKind[c.Kind]; !ok {
+		return fmt.Errorf("unavailable kind of project: %q", c.Kind)
+	}
 	return nil
 }
 
+// checkGoMod checks the directives of go.mod, if any.
+func (c *Conf) checkGoMod() error {
+	c.GoVersion = strings.TrimPrefix(c.GoVersion, "go")
+
+	if c.GoVersion != "" && !reGoVersion.MatchString(c.GoVersion) {
+		return fmt.Errorf("wrong go version: %q", c.GoVersion)
+	}
+	if c.Toolchain != "" && !reToolchain.MatchString(c.Toolchain) {
+		return fmt.Errorf("wrong toolchain: %q", c.Toolchain)
+	}
+
+	// The service uses signal.NotifyContext, added in Go 1.16.
+	if c.Kind == "svc" && c.GoVersion != "" {
+		minor, _ := strconv.Atoi(strings.Split(c.GoVersion, ".")[1])
+		if minor < 16 {
+			return fmt.Errorf("the kind of project %q needs go 1.16 or later", c.Kind)
+		}
+	}
+	return nil
+}
+
+// HeaderCheck checks the configuration needed to render the license header
+// into existing source files. If check is true, the header is only checked
+// (see Project.CheckHeader), so the copyright holder is not required.
+func (c *Conf) HeaderCheck(check bool) error {
+	if c.License == "" {
+		return errors.New("missing required field: license")
+	}
+	if !check {
+		if c.Author == "" && c.Org == "" {
+			return errors.New("missing required field: author or org")
+		}
+		if c.Org != "" && c.Project == "" {
+			return errors.New("the project name is required for an organization")
+		}
+	}
+
+	c.License = strings.ToLower(c.License)
+
+	if err := c.checkLicense(); err != nil {
+		return err
+	}
+	return c.checkSPDX()
+}
+
+// checkSPDX checks the mode to use the SPDX identifier.
+func (c *Conf) checkSPDX() error {
+	c.SPDX = strings.ToLower(c.SPDX)
+
+	switch c.SPDX {
+	case "", SPDXOnly, SPDXAdd:
+		return nil
+	}
+	return fmt.Errorf("unavailable SPDX mode: %q", c.SPDX)
+}
+
 // PostCheck checks and sets to be run after of.get configuration.
 func (c *Conf) PostCheck(interactive, addConfig bool) error {
 	// Email
@@ -206,18 +462,5 @@ func (c *Conf) PostCheck(interactive, addConfig bool) error {
 			return err
 		}
 	}
-
-	c.Email = fmt.Sprintf("%s <%s>",
-		c.Author, strings.Replace(c.Email, "@", " AT ", -1))
-
-	// Adds extra fields to pass to templates.
-	if !addConfig {
-		c.ProjectHeader = strings.Repeat(_HEADER_CHAR, len(c.Project))
-
-		if c.License != "none" {
-			c.FullLicense = ListLicense[ListLowerLicense[c.License]]
-		}
-	}
-
 	return nil
 }
@@ -0,0 +1,67 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeaderCheck(t *testing.T) {
+	tests := []struct {
+		cfg   Conf
+		check bool
+		err   string // part of the error message, if any
+	}{
+		{Conf{License: "mpl", Author: "Jane"}, false, ""},
+		{Conf{License: "mpl", Org: "Acme", Project: "Foo"}, false, ""},
+		{Conf{License: "mpl"}, true, ""},
+		{Conf{License: "mpl", Org: "Acme"}, true, ""},
+		{Conf{Author: "Jane"}, false, "license"},
+		{Conf{}, true, "license"},
+		{Conf{License: "mpl"}, false, "author or org"},
+		{Conf{License: "mpl", Org: "Acme"}, false, "project name"},
+		{Conf{License: "foo", Author: "Jane"}, false, "foo"},
+	}
+
+	for _, tt := range tests {
+		cfg := tt.cfg
+		err := cfg.HeaderCheck(tt.check)
+
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("HeaderCheck(%v) for %+v: unexpected error: %s", tt.check, tt.cfg, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("HeaderCheck(%v) for %+v: got error %v, want one about %q",
+				tt.check, tt.cfg, err, tt.err)
+		}
+	}
+}
+
+func TestCheckGoMod(t *testing.T) {
+	tests := []struct {
+		cfg Conf
+		ok  bool
+	}{
+		{Conf{GoVersion: "1.21"}, true},
+		{Conf{GoVersion: "go1.18.2"}, true},
+		{Conf{GoVersion: "1.x"}, false},
+		{Conf{Toolchain: "go1.21.5"}, true},
+		{Conf{Toolchain: "1.21"}, false},
+		{Conf{Kind: "svc", GoVersion: "1.16"}, true},
+		{Conf{Kind: "svc", GoVersion: "1.18"}, true},
+		{Conf{Kind: "svc", GoVersion: "1.15"}, false},
+		{Conf{Kind: "lib", GoVersion: "1.15"}, true},
+	}
+
+	for _, tt := range tests {
+		cfg := tt.cfg
+		if err := cfg.checkGoMod(); (err == nil) != tt.ok {
+			t.Errorf("checkGoMod for %+v: got error %v, want ok %v", tt.cfg, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const _GO_MOD = "go.mod"
+
+var (
+	// Module path declared in go.mod.
+	reModule = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+
+	// SPDX license expression in a license header.
+	reSPDX = regexp.MustCompile(`SPDX-License-Identifier:\s*(.+?)\s*(?:\*/|-->)?\s*$`)
+
+	// Action of a template, and word of a text, to match license texts.
+	reAction = regexp.MustCompile(`(?s){{.*?}}`)
+	reWord   = regexp.MustCompile(`[a-z0-9]+`)
+
+	// Address of a remote repository, as "git@github.com:tredoe/wizard.git".
+	reRemote = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([a-z0-9.-]+\.[a-z]+)(?::[0-9]+)?[:/](.+?)(?:\.git)?/?$`)
+)
+
+// Detect returns the configuration of the existing project which has the
+// directory dir (see Conf.FromProject).
+func Detect(dir string) (*Conf, error) {
+	c := new(Conf)
+	if err := c.FromProject(dir); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// FromProject sets the fields not set from the existing project which has the
+// directory dir: the project name from the Readme file; the licenses from the
+// license files, or else from the SPDX license identifier in the headers of
+// the source files; the VCS from the directory of its metadata, into the
+// project; the author and email from the configuration of the repository; and
+// the program name and the import path from the module path in go.mod, or else
+// from the remote repository.
+func (c *Conf) FromProject(dir string) error {
+	root, err := projectRoot(dir)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	if c.Project == "" {
+		if name, err := getProjectName(root); err == nil {
+			c.Project = name
+		}
+	}
+
+	if c.License == "" {
+		license, docLicense, err := projectLicenses(root)
+		if err != nil {
+			return err
+		}
+		if license == "" {
+			if license, err = headerLicense(root); err != nil {
+				return err
+			}
+		}
+
+		c.License = license
+		if c.DocLicense == "" {
+			c.DocLicense = docLicense
+		}
+	}
+
+	vcs, repo := projectVCS(abs, root)
+	if c.VCS == "" {
+		c.VCS = vcs
+	}
+
+	var remote string
+	if vcs != "" {
+		conf, err := readConfig(filepath.Join(repo, listConfigVCS[vcs]))
+		if err != nil {
+			return err
+		}
+
+		var author, email string
+		author, email, remote = configVCS(vcs, conf)
+
+		if c.Author == "" {
+			c.Author = author
+		}
+		if c.Email == "" {
+			c.Email = email
+		}
+	}
+
+	module, err := modulePath(root)
+	if err != nil {
+		return err
+	}
+	if module == "" {
+		module = remoteImportPath(remote)
+	}
+	if module != "" {
+		if c.Program == "" {
+			c.Program = path.Base(module)
+		}
+		if len(c.ImportPaths) == 0 && path.Dir(module) != "." {
+			c.ImportPaths = []string{path.Dir(module)}
+		}
+	}
+	if c.Program == "" {
+		c.Program = strings.ToLower(filepath.Base(root))
+	}
+
+	return nil
+}
+
+// * * *
+
+// projectRoot returns the root directory of the project which has the
+// directory dir, that is the nearest one with the file go.mod; else dir.
+func projectRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for d := abs; ; {
+		if _, err = os.Stat(filepath.Join(d, _GO_MOD)); err == nil {
+			return d, nil
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			return abs, nil
+		}
+		d = parent
+	}
+}
+
+// modulePath returns the module path declared in the file go.mod into the
+// directory dir, if any.
+func modulePath(dir string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, _GO_MOD))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if m := reModule.FindSubmatch(data); m != nil {
+		return string(m[1]), nil
+	}
+	return "", nil
+}
+
+// projectVCS returns the VCS used into the directory dir or its parents until
+// the root directory of the project, and the root directory of the repository.
+func projectVCS(dir, root string) (vcs, repo string) {
+	for d := dir; ; {
+		for _, v := range ListVCSsorted {
+			if info, err := os.Stat(filepath.Join(d, "."+v)); err == nil && info.IsDir() {
+				return v, d
+			}
+		}
+
+		parent := filepath.Dir(d)
+		if d == root || parent == d {
+			return "", ""
+		}
+		d = parent
+	}
+}
+
+// projectLicenses returns the license expressions of the source files and of
+// the documentation, got from the license files into the directory dir: the
+// ones named as the license files created by Create, and the ones named as
+// "LICENSE", "COPYING" or similar, whose text is matched against the text of
+// the registered licenses. The Readme file is used to know the licenses of the
+// documentation, and whether the user can choose any license.
+func projectLicenses(dir string) (license, docLicense string, err error) {
+	readme, err := ioutil.ReadFile(filepath.Join(dir, _README))
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", err
+	}
+	codeLine := readmeLine(readme, "+ The source files are")
+	docLine := readmeLine(readme, "+ The documentation is")
+
+	var found []*License
+
+	files, err := licenseFiles(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for _, name := range files {
+		l, err := fileLicense(name)
+		if err != nil {
+			return "", "", err
+		}
+		if l == nil {
+			continue
+		}
+
+		isFound := false
+		for _, v := range found {
+			if v == l {
+				isFound = true
+				break
+			}
+		}
+		if !isFound {
+			found = append(found, l)
+		}
+	}
+
+	// The licenses copied only since another one requires them, which are not
+	// listed in the Readme file.
+	required := make(map[*License]bool)
+	for _, v := range found {
+		for _, id := range v.Requires {
+			if l, ok := LookupLicense(id); ok {
+				required[l] = true
+			}
+		}
+	}
+
+	var code, doc []string
+
+	for _, v := range found {
+		name := "*" + v.Name + "*"
+		if required[v] && !strings.Contains(codeLine, name) && !strings.Contains(docLine, name) {
+			continue
+		}
+		if strings.Contains(docLine, name) && !strings.Contains(codeLine, name) {
+			doc = append(doc, strings.ToLower(v.ID))
+		} else {
+			code = append(code, strings.ToLower(v.ID))
+		}
+	}
+
+	return joinLicenses(code, codeLine), joinLicenses(doc, docLine), nil
+}
+
+// licenseFiles returns the license files into the directory dir, sorted by
+// name.
+func licenseFiles(dir string) ([]string, error) {
+	files := make([]string, 0)
+
+	for _, pattern := range []string{"LICENSE*", "LICENCE*", "COPYING*", "UNLICENSE*"} {
+		list, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range list {
+			if info, err := os.Stat(v); err == nil && info.Mode().IsRegular() {
+				files = append(files, v)
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// fileLicense returns the license of the license file name, got from its name
+// if it is the one created by Create, else from its text. It returns nil if it
+// does not match any registered license.
+func fileLicense(name string) (*License, error) {
+	list := Licenses()
+
+	for _, v := range list {
+		if filepath.Base(name) == v.File() {
+			return v, nil
+		}
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	text := normalizeText(string(data))
+
+	for _, v := range list {
+		src, err := v.text()
+		if err != nil {
+			continue // license without text
+		}
+		if matchLicenseText(text, string(src)) {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// matchLicenseText reports whether the normalized text of a license file
+// matches the text of a license. The actions of the template of the text, as
+// the copyright notice, match any text, and the title before the first one is
+// optional.
+func matchLicenseText(text, license string) bool {
+	parts := reAction.Split(license, -1)
+
+	for i, v := range parts {
+		part := normalizeText(v)
+		if part == " " {
+			continue
+		}
+
+		idx := strings.Index(text, part)
+		if idx == -1 {
+			if i == 0 && len(parts) > 1 {
+				continue
+			}
+			return false
+		}
+		// The last space is kept to match the next part.
+		text = text[idx+len(part)-1:]
+	}
+	return true
+}
+
+// normalizeText returns the words of the text, in lower case, separated by a
+// space and enclosed in spaces, to compare texts whatever their format is.
+func normalizeText(s string) string {
+	return " " + strings.Join(reWord.FindAllString(strings.ToLower(s), -1), " ") + " "
+}
+
+// readmeLine returns the line of the Readme file which starts with prefix.
+func readmeLine(readme []byte, prefix string) string {
+	for _, line := range strings.Split(string(readme), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+// joinLicenses joins the licenses with the operator used in the line of the
+// Readme file which lists them.
+func joinLicenses(licenses []string, line string) string {
+	op := LicenseAnd
+	if strings.HasSuffix(line, ", at your option") {
+		op = LicenseOr
+	}
+	return strings.Join(licenses, " "+op+" ")
+}
+
+// headerLicense returns the license expression got from the first SPDX license
+// identifier found in the headers of the source files into the directory dir,
+// if any.
+func headerLicense(dir string) (string, error) {
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range files {
+		style, _ := commentStyleFor(name)
+
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+
+		for _, line := range strings.Split(string(leadingComments(src, style)), "\n") {
+			m := reSPDX.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+
+			var ids []string
+			op := ""
+
+			for _, v := range strings.Fields(m[1]) {
+				if v == LicenseOr || v == LicenseAnd {
+					op = v
+					continue
+				}
+				if license := spdxLicense(v); license != nil {
+					ids = append(ids, strings.ToLower(license.ID))
+				}
+			}
+			if len(ids) != 0 {
+				return strings.Join(ids, " "+op+" "), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// spdxLicense returns the license with the SPDX identifier id, if any.
+func spdxLicense(id string) *License {
+	for _, v := range Licenses() {
+		if strings.EqualFold(v.SPDX, id) {
+			return v
+		}
+	}
+	return nil
+}
+
+// == Configuration of VCS
+//
+
+// readConfig reads a configuration file in INI format, as the ones of the VCS.
+// It returns the values by section and key, in lower case; the keys out of any
+// section are into the section "". It returns nil if the file does not exist.
+func readConfig(name string) (map[string]map[string]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	conf := map[string]map[string]string{"": {}}
+	section := ""
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "", line[0] == '#', line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			if _, ok := conf[section]; !ok {
+				conf[section] = make(map[string]string)
+			}
+		default:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			conf[section][strings.ToLower(strings.TrimSpace(kv[0]))] =
+				strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		}
+	}
+	return conf, scanner.Err()
+}
+
+// configVCS returns the author, email and remote repository from the
+// configuration conf of the VCS, of a repository or per user.
+func configVCS(vcs string, conf map[string]map[string]string) (author, email, remote string) {
+	if conf == nil {
+		return
+	}
+
+	switch vcs {
+	case "git":
+		author, email = conf["user"]["name"], conf["user"]["email"]
+		remote = conf[`remote "origin"`]["url"]
+	case "hg":
+		author, email = splitAddress(conf["ui"]["username"])
+		remote = conf["paths"]["default"]
+	case "bzr":
+		// The configuration per user has it into the section "DEFAULT".
+		addr := conf[""]["email"]
+		if addr == "" {
+			addr = conf["default"]["email"]
+		}
+		author, email = splitAddress(addr)
+		if remote = conf[""]["push_location"]; remote == "" {
+			remote = conf[""]["parent_location"]
+		}
+	}
+	return
+}
+
+// splitAddress returns the name and email of an address, as
+// "Jonas mg <jonas@example.com>".
+func splitAddress(s string) (name, email string) {
+	if s == "" {
+		return "", ""
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return s, ""
+	}
+	return addr.Name, addr.Address
+}
+
+// remoteImportPath returns the import path of a remote repository, if it has a
+// host name.
+func remoteImportPath(remote string) string {
+	if m := reRemote.FindStringSubmatch(remote); m != nil {
+		return m[1] + "/" + m[2]
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// licenseData returns the text of the builtin license with identifier id, with
+// the copyright notice copyright.
+func licenseData(t *testing.T, id, copyright string) string {
+	data, err := ioutil.ReadFile(filepath.Join(_DATA_DIR, id+".txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Replace(string(data), `{{template "Copyright" .}}`, copyright, 1)
+}
+
+func TestProjectLicenses(t *testing.T) {
+	const copyright = "Copyright (c) 2020, John Doe"
+	mit := licenseData(t, "MIT", copyright)
+
+	tests := []struct {
+		name    string
+		data    string
+		license string
+	}{
+		{"LICENSE-MPL.txt", "foo", "mpl"},
+		{"LICENSE", mit, "mit"},
+		{"LICENSE.md", strings.Replace(mit, "MIT License\n", "", 1), "mit"},
+		{"LICENSE.txt", strings.Replace(mit, "\n", "\r\n", -1), "mit"},
+		{"COPYING", licenseData(t, "GPL", ""), "gpl"},
+		{"LICENSE", licenseData(t, "BSD-2", copyright), "bsd-2"},
+		{"LICENSE", licenseData(t, "BSD-3", copyright), "bsd-3"},
+		{"LICENSE", strings.Replace(mit, "all", "any", -1), ""},
+		{"NOTICE", mit, ""},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "wizard-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, dir, map[string]string{tt.name: tt.data})
+
+		license, _, err := projectLicenses(dir)
+		os.RemoveAll(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if license != tt.license {
+			t.Errorf("projectLicenses with %s = %q, want %q", tt.name, license, tt.license)
+		}
+	}
+}
+
+func TestProjectVCS(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// The repository is out of the project.
+	root := filepath.Join(tmp, "foo")
+	if err = os.MkdirAll(filepath.Join(tmp, ".git"), _DIR_PERM); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, root, map[string]string{"bar/bar.go": "package bar\n"})
+
+	if vcs, repo := projectVCS(filepath.Join(root, "bar"), root); vcs != "" {
+		t.Errorf("projectVCS = %q, %q; want no VCS", vcs, repo)
+	}
+	if vcs, repo := projectVCS(root, tmp); vcs != "git" || repo != tmp {
+		t.Errorf("projectVCS = %q, %q; want %q, %q", vcs, repo, "git", tmp)
+	}
+}
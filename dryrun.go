@@ -0,0 +1,69 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// DryRun renders the project in memory, without writing to disk nor running
+// commands. It prints to w the tree of directories and files planned, with the
+// size of every file in bytes, and the commands to run; and the content of the
+// files if contents is true.
+func (p *Project) DryRun(w io.Writer, contents bool) error {
+	if _, err := p.checkDir(p.targetDir()); err != nil {
+		return fmt.Errorf("directory error: %s", err)
+	}
+
+	fs := NewMemFS()
+	oldFS := p.fs
+	p.fs, p.cmds = fs, nil
+	defer func() { p.fs = oldFS }()
+
+	if err := p.Create(); err != nil {
+		return err
+	}
+	names := fs.Paths()
+
+	for _, name := range names {
+		indent := strings.Repeat("  ", strings.Count(name, "/"))
+
+		if fs.IsDir(name) {
+			fmt.Fprintf(w, "%s%s/\n", indent, path.Base(name))
+		} else {
+			data, _ := fs.ReadFile(name)
+			fmt.Fprintf(w, "%s%s (%d)\n", indent, path.Base(name), len(data))
+		}
+	}
+
+	if len(p.cmds) != 0 {
+		fmt.Fprintf(w, "\nCommands to run into %s:\n\n", p.targetDir())
+		for _, v := range p.cmds {
+			fmt.Fprintf(w, "\t%s\n", v)
+		}
+	}
+
+	if contents {
+		for _, name := range names {
+			if fs.IsDir(name) {
+				continue
+			}
+
+			data, _ := fs.ReadFile(name)
+			fmt.Fprintf(w, "\n==> %s <==\n", name)
+			w.Write(data)
+			if len(data) != 0 && data[len(data)-1] != '\n' {
+				fmt.Fprintln(w)
+			}
+		}
+	}
+
+	return nil
+}
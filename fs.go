@@ -0,0 +1,295 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS represents the file system where the project is created.
+type FS interface {
+	// MkdirAll creates the directory name, along with any necessary parents.
+	MkdirAll(name string, perm os.FileMode) error
+
+	// WriteFile writes data to the file name, creating it with permissions perm.
+	WriteFile(name string, data []byte, perm os.FileMode) error
+
+	// Exists reports whether the file or directory name exists.
+	Exists(name string) (bool, error)
+}
+
+// == OS
+
+// OSFS is the file system of the operating system.
+type OSFS struct{}
+
+func (OSFS) MkdirAll(name string, perm os.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+
+func (OSFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+
+func (OSFS) Exists(name string) (bool, error) {
+	_, err := os.Lstat(name)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// == Memory
+
+// MemFS is a file system in memory.
+type MemFS struct {
+	dirs  map[string]os.FileMode
+	files map[string]memFile
+}
+
+type memFile struct {
+	data []byte
+	mode os.FileMode
+}
+
+// NewMemFS returns an empty file system in memory.
+func NewMemFS() *MemFS {
+	return &MemFS{
+		dirs:  make(map[string]os.FileMode),
+		files: make(map[string]memFile),
+	}
+}
+
+func (m *MemFS) MkdirAll(name string, perm os.FileMode) error {
+	for _, dir := range parentDirs(name) {
+		if _, ok := m.files[dir]; ok {
+			return &os.PathError{Op: "mkdir", Path: dir, Err: errors.New("not a directory")}
+		}
+		if _, ok := m.dirs[dir]; !ok {
+			m.dirs[dir] = perm
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	name = cleanPath(name)
+
+	if _, ok := m.dirs[name]; ok {
+		return &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	if err := m.MkdirAll(path.Dir(name), _DIR_PERM); err != nil {
+		return err
+	}
+
+	m.files[name] = memFile{append([]byte(nil), data...), perm}
+	return nil
+}
+
+func (m *MemFS) Exists(name string) (bool, error) {
+	name = cleanPath(name)
+
+	_, isDir := m.dirs[name]
+	_, isFile := m.files[name]
+	return isDir || isFile, nil
+}
+
+// ReadFile returns the content of the file name.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	f, ok := m.files[cleanPath(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return f.data, nil
+}
+
+// IsDir reports whether name is a directory.
+func (m *MemFS) IsDir(name string) bool {
+	_, ok := m.dirs[cleanPath(name)]
+	return ok
+}
+
+// Paths returns the paths of all directories and files, with slashes, sorted
+// so every directory is followed by its content.
+func (m *MemFS) Paths() []string {
+	names := make([]string, 0, len(m.dirs)+len(m.files))
+	for k := range m.dirs {
+		names = append(names, k)
+	}
+	for k := range m.files {
+		names = append(names, k)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		a, b := strings.Split(names[i], "/"), strings.Split(names[j], "/")
+
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return names
+}
+
+// == Archive
+
+// ArchiveFS is a file system written into an archive, in format zip or tar.
+// The archive has to be closed after of writing the files.
+type ArchiveFS struct {
+	zw *zip.Writer
+	tw *tar.Writer
+	gz *gzip.Writer
+
+	names   map[string]bool
+	modTime time.Time
+}
+
+// NewZipFS returns a file system written into a zip archive.
+func NewZipFS(w io.Writer) *ArchiveFS {
+	return &ArchiveFS{
+		zw:      zip.NewWriter(w),
+		names:   make(map[string]bool),
+		modTime: time.Now(),
+	}
+}
+
+// NewTarFS returns a file system written into a tar archive, compressed with
+// gzip if compress is true.
+func NewTarFS(w io.Writer, compress bool) *ArchiveFS {
+	a := &ArchiveFS{
+		names:   make(map[string]bool),
+		modTime: time.Now(),
+	}
+	if compress {
+		a.gz = gzip.NewWriter(w)
+		w = a.gz
+	}
+	a.tw = tar.NewWriter(w)
+	return a
+}
+
+func (a *ArchiveFS) MkdirAll(name string, perm os.FileMode) error {
+	for _, dir := range parentDirs(name) {
+		if a.names[dir] {
+			continue
+		}
+
+		var err error
+		if a.zw != nil {
+			hdr := &zip.FileHeader{Name: dir + "/", Modified: a.modTime}
+			hdr.SetMode(os.ModeDir | perm)
+			_, err = a.zw.CreateHeader(hdr)
+		} else {
+			err = a.tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     dir + "/",
+				Mode:     int64(perm),
+				ModTime:  a.modTime,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("archive error: %s", err)
+		}
+		a.names[dir] = true
+	}
+	return nil
+}
+
+func (a *ArchiveFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	name = cleanPath(name)
+
+	if a.names[name] {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+	if err := a.MkdirAll(path.Dir(name), _DIR_PERM); err != nil {
+		return err
+	}
+
+	if a.zw != nil {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.modTime}
+		hdr.SetMode(perm)
+
+		w, err := a.zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("archive error: %s", err)
+		}
+		if _, err = w.Write(data); err != nil {
+			return fmt.Errorf("archive error: %s", err)
+		}
+	} else {
+		err := a.tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(perm),
+			Size:     int64(len(data)),
+			ModTime:  a.modTime,
+		})
+		if err != nil {
+			return fmt.Errorf("archive error: %s", err)
+		}
+		if _, err = a.tw.Write(data); err != nil {
+			return fmt.Errorf("archive error: %s", err)
+		}
+	}
+
+	a.names[name] = true
+	return nil
+}
+
+func (a *ArchiveFS) Exists(name string) (bool, error) {
+	return a.names[cleanPath(name)], nil
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (a *ArchiveFS) Close() error {
+	if a.zw != nil {
+		return a.zw.Close()
+	}
+
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if a.gz != nil {
+		return a.gz.Close()
+	}
+	return nil
+}
+
+// * * *
+
+// cleanPath returns the path cleaned and with slashes.
+func cleanPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// parentDirs returns the directory name and its parents, from the top one.
+func parentDirs(name string) []string {
+	name = cleanPath(name)
+
+	dirs := make([]string, 0)
+	for ; name != "." && name != "/"; name = path.Dir(name) {
+		dirs = append([]string{name}, dirs...)
+	}
+	return dirs
+}
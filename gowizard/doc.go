@@ -21,6 +21,15 @@ configuration file in your home directory to have values by default.
 
 	gowizard -i -cfg
 
+The author and email not set by flags nor in the user configuration are got from
+the configuration of the VCS: "~/.gitconfig" or "~/.config/git/config" for Git,
+the username of the section "ui" in "~/.hgrc" for Mercurial, and the email in
+"~/.bazaar/bazaar.conf" or "~/.config/breezy/breezy.conf" for Bazaar.
+
+The license texts are embedded in the program. To use custom texts, they can be
+put into a directory given by the flag *-data*, named as the license (i.e.
+"MPL.txt"); the ones not found there are got from the embedded data.
+
 Create project
 
 By default, the program name (flag *-program*) is named as the project name but
@@ -30,8 +39,214 @@ The flag -import is the import path of your project, but you must substitute the
 project name by "$" since Gowizard uses it to add the name automatically. For
 example: *github.com/tredoe/$*
 
+The file go.mod is created using the import path as module path, or the program
+name when it is not set. The go directive is the version of the Go release used
+to build Gowizard, unless it is set by the flag *-go* (i.e. "1.21"); and the
+toolchain directive is only added when it is set by the flag *-toolchain*.
+
+The flag *-kind* sets the layout of the project:
+
+	lib:    a library (by default)
+	cmd:    a command, with the flags parsing and the documentation in "doc.go"
+	libcmd: a library with a command in the directory "cmd/<program>"
+	svc:    a web service, with the command in the directory "cmd/<program>"
+
+The web service is a HTTP server with graceful shutdown, which has the endpoints
+"/healthz" and "/readyz" in the package "internal/handler", and loads its
+configuration from environment variables in the package "internal/config". It
+also has a Dockerfile to build a container image. It needs go 1.16 or later.
+
+Custom templates
+
+The flag *-templates*, or "templates" in the user configuration, sets a
+directory with custom templates, using the package "text/template" with the
+fields of the type Conf in the package wizard.
+
+The files at the top of the directory named as a builtin template plus
+".tmpl" override it (i.e. "Readme.tmpl"); the builtin templates are: Go, Test,
+Example, Cmd, CmdDoc, SvcMain, SvcHandler, SvcHandlerTest, SvcConfig,
+SvcDockerfile, GoMod, Readme, ReadmeLicense, Authors, Contributors, Changelog,
+Ignore, Header, LicenseHeader, LicenseList and Copyright.
+
+The rest of files are added to the project, keeping their path relative to the
+directory, which is also a template (i.e. "internal/{{.Program}}_util.go"). The
+license header is added with the template "Header".
+
+Template packs
+
+The project is created from a template pack, declared by a manifest in YAML. The
+flag *-pack*, or "pack" in the user configuration, sets the directory of a pack,
+which has the manifest in the file "pack.yml"; else, it is used the default pack
+which creates the projects described above.
+
+	name: mini
+	dirs:
+	  - testdata
+	vars:
+	  - name: db
+	    prompt: Database driver
+	    default: postgres
+	files:
+	  - path: go.mod
+	    template: GoMod              # builtin template
+	  - path: "{{.Program}}.go"
+	    src: lib.go.tmpl             # rendered file into the pack directory
+	  - path: scripts/run.sh
+	    src: run.sh
+	    verbatim: true               # copied without rendering
+	  - path: NOTICE
+	    src: NOTICE
+	    when: {license: [apache]}    # also "vcs" and "kind"
+	  - path: db/postgres.go
+	    src: postgres.go.tmpl
+	    if: '{{eq .Vars.db "postgres"}}'
+	post:
+	  - run: "{{.VCS}} init"
+	    when: {vcs: [bzr, git, hg]}
+
+The paths, the conditions "if" and the commands to run after of creating the
+project are templates too. A file is included when all the conditions "when"
+match and the condition "if" is not rendered to "" or "false".
+
+The variables are passed to templates in the field *Vars*. They are asked in
+the interactive mode, or they are set by the flag *-var*, which can be repeated;
+else it is used the value by default.
+
+	gowizard -pack ~/packs/mini -var db=mysql -var metrics=true ...
+
+A variable can have a type: "string" (by default), "bool", "int" or "choice",
+with the values allowed in "choices"; a regular expression to match in
+"pattern", for strings; and it can be "required".
+
 The way fastest and simple to create it, is using the interactive mode:
 
 	gowizard -i
+
+The project is generated into a staging directory, which is moved into place
+only when all the steps have been done, as the commands of the template pack.
+On any error, the staging directory is removed, and it is reported the step
+which failed.
+
+The project is created into a directory named as the program, unless it is set
+the flag *-dir*. It can be an existing directory if it is empty, excepting the
+metadata of a VCS, as a repository just cloned; or the current one (".").
+
+	git clone https://github.com/tredoe/foo && cd foo
+	gowizard -i -dir .
+
+The flag *-commit* stages all the files created, even the ones matched by the
+file ignore, and creates the initial commit after of initializing the VCS (Git,
+Mercurial or Bazaar), with the author and email as author and committer. The
+message is "Initial commit", unless it is set by the flag *-commitmsg*.
+
+	gowizard -i -vcs git -commit -commitmsg "Start project"
+
+With the flag *-n* (dry run), the project is rendered in memory, printing the
+tree of files with their size in bytes and the commands to run, without writing
+to disk. The flag *-contents* prints also the content of every file.
+
+	gowizard -i -n -contents
+
+The flag *-archive* creates the project into an archive instead of the disk,
+whose format is got from its extension: ".zip", ".tar", ".tar.gz" or ".tgz".
+The files are put into a directory named as the last element of the project
+directory. The commands of the template pack, as the initialization of the VCS,
+are not run.
+
+	gowizard -i -archive foo.zip
+
+Several licenses
+
+The flag *-license* accepts several licenses joined by "OR", when the user can
+choose any of them, or by "AND", when all of them apply. The text of every
+license is copied, and the header lists all them, using the SPDX license
+expression (i.e. "Apache-2.0 OR MIT") if it is set the flag *-spdx*. The text of
+the GPL is also copied with the LGPL, since this one refers to it.
+
+	gowizard -license "apache OR mit" ...
+
+The flag *-doclicense* sets a different license for the documentation, which
+is added to the section "License" of the Readme file.
+
+	gowizard -license mpl -doclicense cc0 ...
+
+Add license header
+
+The command "header" adds the license header to the source files of an existing
+project which have not a copyright notice, skipping the generated files. The
+directory by default is the current one.
+
+The header is commented according to the file extension, so it is also added to
+the files in C, assembler, shell, Python, YAML, SQL, HTML or Markdown, and to
+the files Makefile and Dockerfile.
+
+	gowizard header -license mpl -author "Jonas mg" [dir]
+
+The project name (flag *-name*) is required when the copyright holder is an
+organization.
+
+The flag *-spdx* uses the SPDX license identifier (i.e. "SPDX-License-Identifier:
+MPL-2.0") in the header: "only" to use it instead of the license text, or "add"
+to add it after the license text. It is also used at creating a project.
+
+With the flag *-check*, it only checks that the source files have the header of
+the license given, listing the ones which have not it and exiting with status 1.
+It is useful to be run in continuous integration.
+
+	gowizard header -check -license mpl
+
+Relicense
+
+The command "relicense" changes the license of an existing project. The headers
+rendered from any license of Gowizard are replaced by the header of the new
+license, keeping the copyright notice, with its year and holder. It also replaces
+the license file and the section "License" of the Readme file.
+
+	gowizard relicense -license apache -author "Jonas mg" [dir]
+
+Add files
+
+The command "add" adds a file to an existing project, rendered from the
+templates with the license header. The kind of file is one of:
+
+	pkg:     a package into a new directory, as "internal/cache/cache.go"
+	file:    a source file, as "util.go"
+	test:    a test file, as "util_test.go"
+	example: an example file, as "example_util_test.go"
+
+The name of the package is got from the Go files of the directory, and its
+import path from the module path in go.mod. The test and example functions are
+named by the file, as "TestLru" and "Example_lru" for "lru".
+
+The commands "add" and "header" get the settings not set by flags from the
+project, before of the user configuration: the project name from the Readme
+file; the licenses from the license files, as "LICENSE" or "COPYING", matching
+their text, or else from the SPDX license identifier in the headers; the VCS
+from the directory of its metadata, into the project; the author and email from
+the configuration of the repository; and the import path from go.mod, or else
+from the remote repository.
+
+	gowizard add pkg internal/cache
+	gowizard add test internal/cache/lru
+
+Update
+
+At creating a project, it is recorded into the file ".gowizard.yml" the
+configuration of the project, the version of the template pack, and the hashes
+of the output rendered from the templates; that file has to be committed, and
+in Mercurial it has to be added explicitly since it is matched by the file
+ignore. The copies of that output are kept into the directory ".gowizard",
+which is not committed.
+
+The command "update" renders again the project with that configuration, and
+merges by lines the changes of the templates into the files, keeping the
+changes done to them. The email and the directories of data, templates and
+template pack are got from the flags and the user configuration, since they are
+not recorded; the template pack has to have the name of the one used to create
+the project. The files updated are listed; the ones where both changes overlap
+are marked like in the VCS, and listed as conflicts to resolve, exiting with
+status 1. The files removed from the project are not created again.
+
+	gowizard update [-email -data -templates -pack] [dir]
 */
 package main
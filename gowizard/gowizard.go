@@ -9,7 +9,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/tredoe/dat/question"
@@ -36,16 +38,50 @@ func (i *importPaths) Set(value string) error {
 	return nil
 }
 
-var fImportPath importPaths
+type packVars map[string]string
+
+func (v packVars) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+func (v packVars) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return fmt.Errorf("expected key=value: %q", value)
+	}
+
+	v[strings.TrimSpace(kv[0])] = kv[1]
+	return nil
+}
+
+var (
+	fImportPath importPaths
+	fVars       = make(packVars)
+	fCheck      bool
+	fDryRun     bool
+	fContents   bool
+	fArchive    string
+	fDir        string
+)
 
 func init() {
 	flag.Var(&fImportPath, "import", "base of import path (i.e. github.com/tredoe); colon-separated list")
+	flag.Var(fVars, "var", "variable of the template pack, as key=value; it can be repeated")
+	flag.BoolVar(&fCheck, "check", false, "check the license header instead of adding it (for header command)")
+	flag.BoolVar(&fDryRun, "n", false, "dry run: print the files to create, without writing them")
+	flag.BoolVar(&fContents, "contents", false, "print also the content of the files (for n flag)")
+	flag.StringVar(&fDir, "dir", "", "directory where the project is created, which can be an existing empty one or \".\"; by default, the program name")
+	flag.StringVar(&fArchive, "archive", "", "create the project into an archive, by extension: .zip, .tar, .tar.gz or .tgz")
 }
 
 // * * *
 
 func usage() {
-	fmt.Fprintf(os.Stderr, `Usage: gowizard -i [-cfg]
+	fmt.Fprintf(os.Stderr, `Usage: gowizard -i [-cfg] [-dir dir] [-n [-contents] | -archive file]
+       gowizard header [-check] [-license -spdx -author -org -name] [dir]
+       gowizard relicense [-license -spdx -author -org -name] [dir]
+       gowizard add [-license -spdx -author -org -name] kind name
+       gowizard update [-email -data -templates -pack] [dir]
 
 `)
 	flag.PrintDefaults()
@@ -53,7 +89,13 @@ func usage() {
 }
 
 func main() {
-	cfg, err := initConfig()
+	// The command is the first argument, if it is not a flag.
+	cmd, args := "", os.Args[1:]
+	if len(args) != 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	cfg, err := initConfig(cmd, args)
 	if err != nil {
 		cmdutil.Fatal(err)
 	}
@@ -61,28 +103,149 @@ func main() {
 		os.Exit(0)
 	}
 
-	p, err := wizard.NewProject(cfg)
+	// The configuration of the project is got from the project to update.
+	if cmd == "update" {
+		updated, conflicts, err := wizard.Update(dirArg(), cfg)
+		for _, v := range updated {
+			fmt.Println(v)
+		}
+		if len(conflicts) != 0 {
+			fmt.Fprintln(os.Stderr, "gowizard: files with conflicts to resolve:")
+			for _, v := range conflicts {
+				fmt.Println(v)
+			}
+		}
+		if err != nil {
+			cmdutil.Fatal(err)
+		}
+		if len(conflicts) != 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	p, err := wizard.New(cfg, &wizard.Options{Dir: fDir})
 	if err != nil {
 		cmdutil.Fatal(err)
 	}
 
-	if err = p.Create(); err != nil {
+	switch cmd {
+	case "":
+		if fDryRun {
+			err = p.DryRun(os.Stdout, fContents)
+			break
+		}
+		if fArchive != "" {
+			err = createArchive(p, fArchive)
+			break
+		}
+		err = p.Create()
+	case "header":
+		var files []string
+
+		if !fCheck {
+			files, err = p.AddHeader(dirArg())
+			for _, v := range files {
+				fmt.Println(v)
+			}
+			break
+		}
+
+		if files, err = p.CheckHeader(dirArg()); err == nil && len(files) != 0 {
+			fmt.Fprintln(os.Stderr, "gowizard: files without the license header:")
+			for _, v := range files {
+				fmt.Println(v)
+			}
+			os.Exit(1)
+		}
+	case "add":
+		var file string
+
+		if file, err = p.Add(flag.Arg(0), flag.Arg(1)); err == nil {
+			fmt.Println(file)
+		}
+	case "relicense":
+		var files []string
+
+		files, err = p.Relicense(dirArg())
+		for _, v := range files {
+			fmt.Println(v)
+		}
+	}
+	if err != nil {
 		cmdutil.Fatal(err)
 	}
 }
 
+// createArchive creates the project into the archive name, whose format is got
+// from its extension.
+func createArchive(p *wizard.Project, name string) (err error) {
+	var newFS func(io.Writer) *wizard.ArchiveFS
+
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		newFS = wizard.NewZipFS
+	case strings.HasSuffix(name, ".tar"):
+		newFS = func(w io.Writer) *wizard.ArchiveFS { return wizard.NewTarFS(w, false) }
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		newFS = func(w io.Writer) *wizard.ArchiveFS { return wizard.NewTarFS(w, true) }
+	default:
+		return fmt.Errorf("unknown format of archive: %q", name)
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(name)
+		}
+	}()
+
+	fs := newFS(file)
+	p.SetFS(fs)
+
+	if err = p.Create(); err != nil {
+		return err
+	}
+	if err = fs.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// dirArg returns the directory given in the first argument, or the current one.
+func dirArg() string {
+	if flag.NArg() != 0 {
+		return flag.Arg(0)
+	}
+	return "."
+}
+
 // * * *
 
-// initConfig loads configuration from flags and user configuration.
-// Returns the configuration to nil when it is used the flag "cfg".
-func initConfig() (*wizard.Conf, error) {
+// initConfig loads configuration from flags and user configuration for the
+// command cmd. Returns the configuration to nil when it is used the flag "cfg".
+func initConfig(cmd string, args []string) (*wizard.Conf, error) {
 	var (
 		fName    = flag.String("name", "", "project name")
-		fLicense = flag.String("license", "", "license covering the program")
+		fLicense = flag.String("license", "", `license covering the program; several ones joined by "OR" or "AND" (i.e. "apache OR mit")`)
+		fDocLic  = flag.String("doclicense", "", "license covering the documentation, if it is different")
+		fSPDX    = flag.String("spdx", "", `SPDX license identifier in header: "only" instead of the license text, or "add" after it`)
 		fAuthor  = flag.String("author", "", "author's name")
 		fEmail   = flag.String("email", "", "author's email")
 		fVCS     = flag.String("vcs", "", "version control system")
+		fKind    = flag.String("kind", "", `kind of project layout; "lib" (by default), "cmd", "libcmd" or "svc"`)
 		fOrg     = flag.String("org", "", "organization holder of the copyright")
+		fData    = flag.String("data", "", "directory with custom license texts, named as the license (i.e. MPL.txt)")
+		fTmpl    = flag.String("templates", "", "directory with custom templates, to override the builtin ones or add files")
+		fPack    = flag.String("pack", "", "directory with the template pack to create the project, declared in pack.yml")
+		fGo      = flag.String("go", "", "go version of the go directive in go.mod (default is the Go release used to build gowizard)")
+		fToolch  = flag.String("toolchain", "", "toolchain directive in go.mod (i.e. go1.21.5), if any")
+		fCommit  = flag.Bool("commit", false, "create the initial commit after of initializing the VCS")
+		fMsg     = flag.String("commitmsg", "", `message of the initial commit (default "Initial commit")`)
 
 		fConfig      = flag.Bool("cfg", false, "add the user configuration file")
 		fInteractive = flag.Bool("i", false, "interactive mode")
@@ -90,29 +253,44 @@ func initConfig() (*wizard.Conf, error) {
 		// Listing
 		fListLicense = flag.Bool("ll", false, "list the available licenses (for license flag)")
 		fListVCS     = flag.Bool("lv", false, "list the available version control systems (for vcs flag)")
+		fListKind    = flag.Bool("lk", false, "list the available kinds of project (for kind flag)")
 	)
 
 	// == Parse the flags
 	flag.Usage = usage
-	flag.Parse()
+	flag.CommandLine.Parse(args)
 
-	if flag.NFlag() == 0 {
+	switch cmd {
+	case "":
+		if flag.NFlag() == 0 {
+			usage()
+		}
+	case "header", "relicense":
+	case "add":
+		if flag.NArg() != 2 {
+			usage()
+		}
+	case "update":
+		if flag.NArg() > 1 {
+			usage()
+		}
+	default:
 		usage()
 	}
 
 	// == Listing
 	if *fListLicense {
 		maxLen := 0
-		for _, v := range wizard.ListLicenseSorted {
-			if len(v) > maxLen {
-				maxLen = len(v)
+		for _, v := range wizard.Licenses() {
+			if len(v.ID) > maxLen {
+				maxLen = len(v.ID)
 			}
 		}
 
 		fmt.Print("  = Licenses\n\n")
-		for _, v := range wizard.ListLicenseSorted {
-			fmt.Printf("  %s: %s%s\n",
-				v, strings.Repeat(" ", maxLen-len(v)), wizard.ListLicense[v],
+		for _, v := range wizard.Licenses() {
+			fmt.Printf("  %s: %s%s (%s)\n",
+				v.ID, strings.Repeat(" ", maxLen-len(v.ID)), v.Name, v.SPDX,
 			)
 		}
 	}
@@ -132,7 +310,23 @@ func initConfig() (*wizard.Conf, error) {
 		}
 	}
 
-	if *fListLicense || *fListVCS {
+	if *fListKind {
+		maxLen := 0
+		for _, v := range wizard.ListKindSorted {
+			if len(v) > maxLen {
+				maxLen = len(v)
+			}
+		}
+
+		fmt.Print("  = Kinds of project\n\n")
+		for _, v := range wizard.ListKindSorted {
+			fmt.Printf("  %s: %s%s\n",
+				v, strings.Repeat(" ", maxLen-len(v)), wizard.ListKind[v],
+			)
+		}
+	}
+
+	if *fListLicense || *fListVCS || *fListKind {
 		return nil, nil
 	}
 
@@ -140,11 +334,37 @@ func initConfig() (*wizard.Conf, error) {
 	cfg := &wizard.Conf{
 		Program:     *fName,
 		License:     *fLicense,
+		DocLicense:  *fDocLic,
+		SPDX:        *fSPDX,
 		Author:      *fAuthor,
 		Email:       *fEmail,
 		VCS:         *fVCS,
+		Kind:        *fKind,
 		ImportPaths: fImportPath,
 		Org:         *fOrg,
+		DataDir:     *fData,
+		TemplateDir: *fTmpl,
+		Pack:        *fPack,
+		GoVersion:   *fGo,
+		Toolchain:   *fToolch,
+		Commit:      *fCommit,
+		CommitMsg:   *fMsg,
+	}
+	if len(fVars) != 0 {
+		cfg.Vars = fVars
+	}
+
+	cfg.Project = *fName
+
+	// The project settings are preferred to the user configuration.
+	switch cmd {
+	case "add":
+		err = cfg.FromProject(".")
+	case "header":
+		err = cfg.FromProject(dirArg())
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Get configuration per user, if any.
@@ -153,7 +373,20 @@ func initConfig() (*wizard.Conf, error) {
 			return nil, err
 		}
 	}
-	cfg.Project = *fName
+	// The author and email by default are got from the VCS.
+	if err = cfg.VCSConfig(); err != nil {
+		return nil, err
+	}
+	if cmd == "update" {
+		return cfg, nil
+	}
+
+	if cmd == "header" || cmd == "relicense" || cmd == "add" {
+		if err = cfg.HeaderCheck(cmd == "header" && fCheck); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
 
 	if err = cfg.PreCheck(*fInteractive, *fConfig); err != nil {
 		return nil, err
@@ -195,6 +428,7 @@ func interactive(c *wizard.Conf, addConfig bool) (err error) {
 			"email",
 			"license",
 			"vcs",
+			"kind",
 			"import",
 		}
 	}
@@ -259,11 +493,22 @@ func interactive(c *wizard.Conf, addConfig bool) (err error) {
 			)
 			c.Email, err = q.ReadString()
 		case "license":
+			licenses := wizard.Licenses()
+			ids := make([]string, len(licenses))
+			for i, v := range licenses {
+				ids[i] = v.ID
+			}
+
+			defaultLicense := ""
+			if l, ok := wizard.LookupLicense(c.License); ok {
+				defaultLicense = l.ID
+			}
+
 			q.Prompt(f.Usage,
 				valid.String(),
-				valid.NewScheme().SetDefault(wizard.ListLowerLicense[c.License]),
+				valid.NewScheme().SetDefault(defaultLicense),
 			)
-			c.License, err = q.ChoiceString(wizard.ListLicenseSorted)
+			c.License, err = q.ChoiceString(ids)
 			// It is got in upper case
 			c.License = strings.ToLower(c.License)
 		case "vcs":
@@ -272,6 +517,17 @@ func interactive(c *wizard.Conf, addConfig bool) (err error) {
 				valid.NewScheme().SetDefault(c.VCS),
 			)
 			c.VCS, err = q.ChoiceString(wizard.ListVCSsorted)
+		case "kind":
+			defaultKind := c.Kind
+			if defaultKind == "" {
+				defaultKind = "lib"
+			}
+
+			q.Prompt(f.Usage,
+				valid.String(),
+				valid.NewScheme().SetDefault(defaultKind),
+			)
+			c.Kind, err = q.ChoiceString(wizard.ListKindSorted)
 		case "import":
 			if addConfig {
 				q.Prompt(f.Usage,
@@ -308,6 +564,74 @@ func interactive(c *wizard.Conf, addConfig bool) (err error) {
 		}
 	}
 
+	if !addConfig {
+		if err = interactiveVars(q, c); err != nil {
+			return err
+		}
+	}
+
 	fmt.Println()
 	return nil
 }
+
+// interactiveVars asks for the variables of the template pack which have not
+// been set in the flag "var".
+func interactiveVars(q *question.Q, c *wizard.Conf) (err error) {
+	vars, err := c.PackVars()
+	if err != nil {
+		return err
+	}
+	if c.Vars == nil {
+		c.Vars = make(map[string]string)
+	}
+
+	for _, v := range vars {
+		if _, ok := fVars[v.Name]; ok {
+			continue
+		}
+
+		defaultValue := v.Default
+		if value, ok := c.Vars[v.Name]; ok {
+			defaultValue = value
+		}
+		scheme := valid.NewScheme().SetDefault(defaultValue)
+		if v.Required {
+			scheme = scheme.Required()
+		}
+
+		value := ""
+		switch v.Type {
+		case wizard.VarBool:
+			b, _ := strconv.ParseBool(defaultValue)
+
+			q.Prompt(v.Prompt,
+				valid.Bool(),
+				valid.NewScheme().SetDefault(b),
+			)
+			if b, err = q.ReadBool(); err == nil {
+				value = strconv.FormatBool(b)
+			}
+		case wizard.VarChoice:
+			q.Prompt(v.Prompt,
+				valid.String(),
+				scheme,
+			)
+			value, err = q.ChoiceString(v.Choices)
+		default:
+			q.Prompt(v.Prompt,
+				valid.String(),
+				scheme,
+			)
+			value, err = q.ReadString()
+		}
+		if err != nil {
+			return err
+		}
+
+		if c.Vars[v.Name], err = v.Check(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,527 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var (
+	// Generated files, according to the convention in "go generate", for any
+	// comment style.
+	reGenerated = regexp.MustCompile(`(?m)^\S* ?Code generated .* DO NOT EDIT\.`)
+
+	// Copyright notices rendered by the templates "Copyright", and the SPDX
+	// license identifier.
+	reCopyright = regexp.MustCompile(`\b(Copyright|Written in|SPDX-License-Identifier)\b`)
+
+	// Section "License" in the Readme file, until the next section or the
+	// footer added by the template "Readme".
+	reReadmeLicense = regexp.MustCompile(`(?s)\n## License\n.*?(\n## |\n\* \* \*\n|\z)`)
+)
+
+// Marks to render the header, to be replaced by patterns which match any
+// copyright notice, list of licenses, and SPDX license expression.
+const (
+	_COPYRIGHT_MARK = "\x00copyright\x00"
+	_LICENSES_MARK  = "\x00licenses\x00"
+	_SPDX_MARK      = "\x00spdx\x00"
+)
+
+// AddHeader adds the license header to the source files into the directory
+// tree rooted at dir which have not a copyright notice. The generated files are
+// skipped.
+//
+// The header is commented according to the file extension (see
+// ListCommentStyle), and it is put at the top of the file, before the build
+// constraints, if any, but after the interpreter directive "#!" in scripts or
+// the XML declaration. Returns the files modified.
+func (p *Project) AddHeader(dir string) ([]string, error) {
+	p.parseLicense()
+
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	modified := make([]string, 0)
+
+	for _, name := range files {
+		style, _ := commentStyleFor(name)
+
+		info, err := os.Stat(name)
+		if err != nil {
+			return modified, err
+		}
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return modified, err
+		}
+		if isGenerated(src) || hasHeader(src, style) {
+			continue
+		}
+
+		p.setComment(style)
+		header, err := p.renderHeader()
+		if err != nil {
+			return modified, err
+		}
+
+		start := headerStart(src)
+		first, src := src[:start], src[start:]
+
+		dst := make([]byte, 0, len(first)+len(header)+1+len(src))
+		dst = append(dst, first...)
+		dst = append(dst, header...)
+		dst = append(dst, '\n')
+		dst = append(dst, bytes.TrimLeft(src, "\n")...)
+
+		if err = ioutil.WriteFile(name, dst, info.Mode()); err != nil {
+			return modified, fmt.Errorf("header error: %s", err)
+		}
+		modified = append(modified, name)
+	}
+
+	return modified, nil
+}
+
+// CheckHeader checks that the source files into the directory tree rooted at
+// dir have the license header, excepting the generated files. The copyright
+// notice is matched whatever the year and holder are.
+//
+// Returns the files which have not the header.
+func (p *Project) CheckHeader(dir string) ([]string, error) {
+	p.parseLicense()
+
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	wrong := make([]string, 0)
+	patterns := make(map[CommentStyle][]*regexp.Regexp)
+
+	for _, name := range files {
+		style, _ := commentStyleFor(name)
+
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return wrong, err
+		}
+		if isGenerated(src) {
+			continue
+		}
+
+		list, ok := patterns[style]
+		if !ok {
+			for _, v := range append([]CommentStyle{style}, altCommentStyles[style]...) {
+				p.setComment(v)
+				re, err := p.headerPattern(false)
+				if err != nil {
+					return wrong, err
+				}
+				list = append(list, re)
+			}
+			patterns[style] = list
+		}
+
+		if !matchAny(list, leadingComments(src, style)) {
+			wrong = append(wrong, name)
+		}
+	}
+
+	return wrong, nil
+}
+
+// Relicense changes the license of the project into the directory dir to the
+// one set in the configuration.
+//
+// The headers of the source files rendered from any license template are
+// replaced by the header of the new license, keeping the copyright notice. The
+// license file and the section "License" of the Readme file are updated too.
+// Returns the source files modified.
+func (p *Project) Relicense(dir string) ([]string, error) {
+	p.parseLicense()
+
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	modified := make([]string, 0)
+	patterns := make(map[CommentStyle][]*regexp.Regexp)
+
+	for _, name := range files {
+		style, _ := commentStyleFor(name)
+
+		info, err := os.Stat(name)
+		if err != nil {
+			return modified, err
+		}
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return modified, err
+		}
+		if isGenerated(src) {
+			continue
+		}
+
+		list, ok := patterns[style]
+		if !ok {
+			for _, v := range append([]CommentStyle{style}, altCommentStyles[style]...) {
+				alt, err := p.licensePatterns(v)
+				if err != nil {
+					return modified, err
+				}
+				list = append(list, alt...)
+			}
+			patterns[style] = list
+		}
+
+		var loc []int
+		leading := leadingComments(src, style)
+
+		for _, re := range list {
+			if loc = re.FindSubmatchIndex(leading); loc != nil {
+				break
+			}
+		}
+		if loc == nil {
+			continue
+		}
+
+		p.setComment(style)
+		header, err := p.renderHeaderWith(src[loc[2]:loc[3]])
+		if err != nil {
+			return modified, err
+		}
+
+		dst := make([]byte, 0, len(src)-(loc[1]-loc[0])+len(header))
+		dst = append(dst, src[:loc[0]]...)
+		dst = append(dst, header...)
+		dst = append(dst, src[loc[1]:]...)
+
+		if bytes.Equal(dst, src) {
+			continue
+		}
+		if err = ioutil.WriteFile(name, dst, info.Mode()); err != nil {
+			return modified, fmt.Errorf("header error: %s", err)
+		}
+		modified = append(modified, name)
+	}
+
+	// == License file
+
+	oldLicenses, err := filepath.Glob(filepath.Join(dir, "LICENSE-*.txt"))
+	if err != nil {
+		return modified, err
+	}
+	for _, v := range oldLicenses {
+		if err = os.Remove(v); err != nil {
+			return modified, fmt.Errorf("license error: %s", err)
+		}
+	}
+	if err = p.copyLicense(dir); err != nil {
+		return modified, err
+	}
+
+	return modified, p.relicenseReadme(dir)
+}
+
+// licensePatterns returns the patterns of the headers of all licenses for the
+// comment style, with and without the SPDX identifier, followed by the ones of
+// the headers for several licenses. The longest headers are the first ones.
+//
+// Since the header of license "none" without SPDX identifier is only the
+// copyright notice, it is the last one to be matched.
+func (p *Project) licensePatterns(style CommentStyle) ([]*regexp.Regexp, error) {
+	list := Licenses()
+	patterns := make([]*regexp.Regexp, 0, len(list)*3)
+	var copyrightOnly *regexp.Regexp
+
+	for _, mode := range []string{SPDXAdd, "", SPDXOnly} {
+		for _, v := range list {
+			cfg := *p.cfg
+			cfg.License = strings.ToLower(v.ID)
+			cfg.SPDX = mode
+
+			old := &Project{tmpl: new(template.Template), cfg: &cfg, data: &Data{Conf: &cfg}}
+			old.parseLicense()
+			old.setComment(style)
+
+			re, err := old.headerPattern(false)
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg.License == "none" && mode == "" {
+				copyrightOnly = re
+			} else {
+				patterns = append(patterns, re)
+			}
+		}
+
+		for _, op := range []string{LicenseOr, LicenseAnd} {
+			cfg := *p.cfg
+			cfg.SPDX = mode
+
+			old := &Project{tmpl: new(template.Template), cfg: &cfg, data: &Data{Conf: &cfg}}
+			old.parseHeader(nil, op)
+			old.setComment(style)
+
+			re, err := old.headerPattern(true)
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, re)
+		}
+	}
+
+	return append(patterns, copyrightOnly), nil
+}
+
+// relicenseReadme replaces the section "License" of the Readme file into the
+// directory dir, if any, by the one rendered for the actual license.
+func (p *Project) relicenseReadme(dir string) error {
+	readme := filepath.Join(dir, _README)
+
+	src, err := ioutil.ReadFile(readme)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	info, err := os.Stat(readme)
+	if err != nil {
+		return err
+	}
+
+	p.tmpl = template.Must(p.tmpl.New("ReadmeLicense").Parse(tmplReadmeLicense))
+
+	var buf bytes.Buffer
+	if err = p.tmpl.ExecuteTemplate(&buf, "ReadmeLicense", p.data); err != nil {
+		return fmt.Errorf("execution failed: %s", err)
+	}
+	section := buf.Bytes()
+
+	var dst []byte
+	if loc := reReadmeLicense.FindSubmatchIndex(src); loc != nil {
+		dst = append(dst, src[:loc[0]]...)
+		dst = append(dst, section...)
+		dst = append(dst, src[loc[2]:]...)
+	} else {
+		// Before of the footer, if any.
+		i := bytes.LastIndex(src, []byte("\n* * *\n"))
+		if i == -1 {
+			i = len(src)
+		}
+		dst = append(dst, src[:i]...)
+		dst = append(dst, section...)
+		dst = append(dst, src[i:]...)
+	}
+
+	if err = ioutil.WriteFile(readme, dst, info.Mode()); err != nil {
+		return fmt.Errorf("readme error: %s", err)
+	}
+	return nil
+}
+
+// headerPattern returns a regular expression which matches the template
+// "Header" rendered, with any copyright notice. If anyLicense is true, it also
+// matches any list of licenses and SPDX license expression.
+func (p *Project) headerPattern(anyLicense bool) (*regexp.Regexp, error) {
+	tmpl, err := p.tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("parsing error: %s", err)
+	}
+	if _, err = tmpl.New("Copyright").Parse(_COPYRIGHT_MARK); err != nil {
+		return nil, fmt.Errorf("parsing error: %s", err)
+	}
+
+	data := *p.data
+	if anyLicense {
+		if _, err = tmpl.New("LicenseList").Parse(_LICENSES_MARK); err != nil {
+			return nil, fmt.Errorf("parsing error: %s", err)
+		}
+		data.SPDXID = _SPDX_MARK
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.ExecuteTemplate(&buf, "Header", &data); err != nil {
+		return nil, fmt.Errorf("execution failed: %s", err)
+	}
+
+	expr := strings.Replace(regexp.QuoteMeta(buf.String()), _COPYRIGHT_MARK,
+		`((?:Copyright|Written in) .+)`, 1)
+	if anyLicense {
+		expr = strings.Replace(expr, _LICENSES_MARK,
+			`(?:`+regexp.QuoteMeta(data.Comment+"   + ")+`.*\n)+`, 1)
+		expr = strings.Replace(expr, _SPDX_MARK, `.+`, 1)
+	}
+	return regexp.Compile(`(?m)^` + expr)
+}
+
+// renderHeader returns the template "Header" rendered.
+func (p *Project) renderHeader() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := p.tmpl.ExecuteTemplate(&buf, "Header", p.data); err != nil {
+		return nil, fmt.Errorf("execution failed: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderHeaderWith returns the template "Header" rendered with the copyright
+// notice given, instead of the one got from the configuration.
+func (p *Project) renderHeaderWith(copyright []byte) ([]byte, error) {
+	tmpl, err := p.tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("parsing error: %s", err)
+	}
+	if _, err = tmpl.New("Copyright").Parse(
+		"{{" + strconv.Quote(string(copyright)) + "}}"); err != nil {
+		return nil, fmt.Errorf("parsing error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.ExecuteTemplate(&buf, "Header", p.data); err != nil {
+		return nil, fmt.Errorf("execution failed: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// * * *
+
+// sourceFiles returns the source files with a known comment style into the
+// directory tree rooted at dir. Like the go tool, it skips the directories
+// "testdata" and the ones started with "." or "_".
+//
+// The information files and the metadata created by Create are skipped, since
+// they are not sources.
+func sourceFiles(dir string) ([]string, error) {
+	files := make([]string, 0)
+
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		base := info.Name()
+		if info.IsDir() {
+			if name == dir {
+				return nil
+			}
+			if base == "testdata" ||
+				strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() || base == _README || base == _META_FILE ||
+			strings.HasSuffix(base, ".txt.md") {
+			return nil
+		}
+		if _, ok := commentStyleFor(name); ok {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk error: %s", err)
+	}
+
+	return files, nil
+}
+
+// isGenerated reports whether the source was generated by a tool.
+func isGenerated(src []byte) bool {
+	return reGenerated.Match(src)
+}
+
+// leadingComments returns the comments at the start of the source, including
+// the blank lines, according to the comment style and its alternative ones
+// (see altCommentStyles). The first line is skipped if it is the one kept
+// before the header (see headerStart), but it is returned too.
+func leadingComments(src []byte, style CommentStyle) []byte {
+	styles := append([]CommentStyle{style}, altCommentStyles[style]...)
+	blockEnd := "" // end of the block comment open, if any
+
+	for end := headerStart(src); end < len(src); {
+		next := len(src)
+		if i := bytes.IndexByte(src[end:], '\n'); i != -1 {
+			next = end + i + 1
+		}
+		line := strings.TrimSpace(string(src[end:next]))
+
+		switch {
+		case blockEnd != "":
+			if strings.Contains(line, blockEnd) {
+				blockEnd = ""
+			}
+		case line == "":
+		case style.Start == "" && strings.HasPrefix(line, style.Line):
+		default:
+			block, ok := blockStyle(line, styles)
+			if !ok {
+				return src[:end]
+			}
+			if e := strings.TrimSpace(block.End); !strings.Contains(line[len(block.Start):], e) {
+				blockEnd = e
+			}
+		}
+
+		end = next
+	}
+
+	return src
+}
+
+// headerStart returns the position of src where the license header is put:
+// after the first line if it is the interpreter directive "#!" of a script or
+// the XML declaration, since they have to be at the top.
+func headerStart(src []byte) int {
+	if bytes.HasPrefix(src, []byte("#!")) || bytes.HasPrefix(src, []byte("<?xml")) {
+		if i := bytes.IndexByte(src, '\n'); i != -1 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// blockStyle returns the comment style, from styles, whose block comment is
+// started by line.
+func blockStyle(line string, styles []CommentStyle) (CommentStyle, bool) {
+	for _, v := range styles {
+		if v.Start != "" && strings.HasPrefix(line, v.Start) {
+			return v, true
+		}
+	}
+	return CommentStyle{}, false
+}
+
+// matchAny reports whether src matches any of the patterns.
+func matchAny(patterns []*regexp.Regexp, src []byte) bool {
+	for _, re := range patterns {
+		if re.Match(src) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHeader reports whether the source has a copyright notice in the comments
+// at its start, according to the comment style.
+func hasHeader(src []byte, style CommentStyle) bool {
+	return reCopyright.Match(leadingComments(src, style))
+}
@@ -0,0 +1,326 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Header of the license MPL in a block comment.
+const blockHeaderMPL = `/*
+ * Copyright 2015 Jane Doe
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+`
+
+// writeFiles writes the files, by name relative to the directory dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(name), _DIR_PERM); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), _FILE_PERM); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFile returns the content of the file name.
+func readFile(t *testing.T, name string) string {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// headerProject returns a project to handle the headers, for the license given.
+func headerProject(t *testing.T, license string) *Project {
+	cfg := testConf("lib")
+	cfg.License = license
+
+	p, err := New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLeadingComments(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		leading string
+	}{
+		{"foo.go", "// Copyright\n//\n// MPL\n\npackage foo\n", "// Copyright\n//\n// MPL\n\n"},
+		{"foo.go", "/*\n * Copyright\n */\n\npackage foo\n", "/*\n * Copyright\n */\n\n"},
+		{"foo.go", "/* Copyright */\n// +build linux\n\npackage foo\n", "/* Copyright */\n// +build linux\n\n"},
+		{"foo.go", "package foo // Copyright\n", ""},
+		{"foo.c", "/* Copyright\n */\nint x;\n", "/* Copyright\n */\n"},
+		{"foo.c", "// Copyright\nint x;\n", ""},
+		{"foo.sh", "# Copyright\n/* foo */\n", "# Copyright\n"},
+	}
+
+	for _, tt := range tests {
+		style, _ := commentStyleFor(tt.name)
+
+		if got := string(leadingComments([]byte(tt.src), style)); got != tt.leading {
+			t.Errorf("leadingComments(%q) in %s = %q, want %q", tt.src, tt.name, got, tt.leading)
+		}
+	}
+}
+
+func TestHeaderBlockComment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := blockHeaderMPL + "package foo\n"
+	writeFiles(t, dir, map[string]string{"foo.go": src})
+	p := headerProject(t, "mpl")
+
+	wrong, err := p.CheckHeader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wrong) != 0 {
+		t.Errorf("CheckHeader: got files without header %v", wrong)
+	}
+
+	modified, err := p.AddHeader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modified) != 0 {
+		t.Errorf("AddHeader: got files modified %v", modified)
+	}
+	if got := readFile(t, filepath.Join(dir, "foo.go")); got != src {
+		t.Errorf("AddHeader: got\n%s\nwant\n%s", got, src)
+	}
+}
+
+func TestHeaderFirstLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The first lines which have to be kept at the top.
+	files := map[string]string{
+		"run.sh":  "#!/bin/sh\n",
+		"foo.xml": `<?xml version="1.0" encoding="UTF-8"?>` + "\n",
+	}
+	src := make(map[string]string)
+	for name, first := range files {
+		src[name] = first + "\n" + "data\n"
+	}
+	writeFiles(t, dir, src)
+	p := headerProject(t, "mpl")
+
+	modified, err := p.AddHeader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modified) != len(files) {
+		t.Errorf("AddHeader: got files modified %v", modified)
+	}
+	for name, first := range files {
+		got := readFile(t, filepath.Join(dir, name))
+		style, _ := commentStyleFor(name)
+
+		if !strings.HasPrefix(got, first) || !hasHeader([]byte(got), style) {
+			t.Errorf("AddHeader: got\n%s", got)
+		}
+	}
+
+	wrong, err := p.CheckHeader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wrong) != 0 {
+		t.Errorf("CheckHeader: got files without header %v", wrong)
+	}
+	if modified, err = p.AddHeader(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(modified) != 0 {
+		t.Errorf("AddHeader: got files modified again %v", modified)
+	}
+}
+
+func TestCheckHeaderProject(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "foo")
+
+	p, err := New(testConf("libcmd"), &Options{Dir: dir, Output: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Any copyright holder is matched at checking.
+	if p, err = New(&Conf{License: "mpl"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	wrong, err := p.CheckHeader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wrong) != 0 {
+		t.Errorf("CheckHeader: got files without header %v", wrong)
+	}
+}
+
+// licenseFile returns the name of the file with the text of the license with
+// identifier id.
+func licenseFile(t *testing.T, id string) string {
+	l, ok := LookupLicense(id)
+	if !ok {
+		t.Fatalf("license not found: %q", id)
+	}
+	return l.File()
+}
+
+// testHeader returns the license header rendered for the configuration cfg,
+// with the year of the copyright, and commented in the style given.
+func testHeader(t *testing.T, cfg Conf, year int, style CommentStyle) string {
+	p, err := New(&cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.year = year
+	p.parseLicense()
+	p.setComment(style)
+
+	header, err := p.renderHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(header)
+}
+
+func TestRelicense(t *testing.T) {
+	const (
+		source   = "\npackage foo\n"
+		noHeader = "package foo\n\nfunc Bar() {}\n"
+	)
+	thisYear := time.Now().Year()
+
+	tests := []struct {
+		msg      string
+		from, to Conf
+		year     int
+		style    CommentStyle // of the old header
+	}{
+		{
+			"GPL to MPL, another author",
+			Conf{License: "gpl", Author: "Jane Doe"},
+			Conf{License: "mpl", Author: "John Roe"},
+			thisYear, styleSlash,
+		},
+		{
+			"MPL to Apache, old year",
+			Conf{License: "mpl", Author: "Jane Doe"},
+			Conf{License: "apache", Author: "Jane Doe"},
+			2015, styleSlash,
+		},
+		{
+			"organization",
+			Conf{License: "gpl", Org: "Acme", Project: "Foo"},
+			Conf{License: "mpl", Org: "Acme", Project: "Foo"},
+			2018, styleSlash,
+		},
+		{
+			"author kept, not organization",
+			Conf{License: "apache", Author: "Jane Doe"},
+			Conf{License: "mpl", Org: "Acme", Project: "Foo"},
+			2019, styleSlash,
+		},
+		{
+			"SPDX identifier",
+			Conf{License: "mit", Author: "Jane Doe", SPDX: SPDXAdd},
+			Conf{License: "mpl", Author: "Jane Doe", SPDX: SPDXOnly},
+			2020, styleSlash,
+		},
+		{
+			"block comment",
+			Conf{License: "mpl", Author: "Jane Doe"},
+			Conf{License: "apache", Author: "Jane Doe"},
+			2016, styleC,
+		},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "wizard-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, dir, map[string]string{
+			"foo.go":                        testHeader(t, tt.from, tt.year, tt.style) + source,
+			"bar.go":                        noHeader,
+			licenseFile(t, tt.from.License): "old license\n",
+		})
+
+		to := tt.to
+		p, err := New(&to, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modified, err := p.Relicense(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The copyright notice is kept, with its year and holder.
+		holder := tt.to
+		holder.Author, holder.Org, holder.Project = tt.from.Author, tt.from.Org, tt.from.Project
+
+		want := testHeader(t, holder, tt.year, styleSlash) + source
+		if got := readFile(t, filepath.Join(dir, "foo.go")); got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.msg, got, want)
+		}
+		if len(modified) != 1 || filepath.Base(modified[0]) != "foo.go" {
+			t.Errorf("%s: got files modified %v, want foo.go", tt.msg, modified)
+		}
+
+		// The files without header are left unchanged.
+		if got := readFile(t, filepath.Join(dir, "bar.go")); got != noHeader {
+			t.Errorf("%s: file without header changed to\n%s", tt.msg, got)
+		}
+
+		licenses, err := filepath.Glob(filepath.Join(dir, "LICENSE-*.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantLicense := licenseFile(t, tt.to.License)
+		if len(licenses) != 1 || filepath.Base(licenses[0]) != wantLicense {
+			t.Errorf("%s: got license files %v, want %s", tt.msg, licenses, wantLicense)
+		}
+
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// License represents a license which can cover a project.
+type License struct {
+	ID   string // identifier used in the configuration; case insensitive
+	Name string // full name, showed in the Readme file
+	SPDX string // SPDX license identifier
+
+	// Header is the template of the license header, where every line starts
+	// with "{{.Comment}}" and the copyright notice is got from the template
+	// "Copyright". See the builtin ones in template.go.
+	Header string
+
+	// Text is the full text of the license, copied to the project. If it is
+	// empty, it is got from the data directory, in the file named as the
+	// identifier with extension ".txt".
+	Text string
+
+	// Requires has the identifiers of the licenses whose texts are also copied,
+	// since this one refers to them, as the GPL by the LGPL.
+	Requires []string
+
+	Notice       bool // the text includes the copyright notice, so it is rendered
+	Authors      bool // the copyright holders are listed in the file AUTHORS
+	PublicDomain bool // the work is dedicated to the public domain, "Written in ..."
+}
+
+// Licenses registered, by identifier in lower case.
+var (
+	licensesMu sync.RWMutex
+	licenses   = make(map[string]*License)
+)
+
+// Register makes a license available to be used in the projects. If there is
+// a license with the same identifier, then it is replaced.
+func Register(l *License) error {
+	if l.ID == "" || l.Name == "" {
+		return errors.New("Register: license without identifier or name")
+	}
+	if _, err := template.New("").Parse(l.Header); err != nil {
+		return fmt.Errorf("Register: header of license %q: %s", l.ID, err)
+	}
+	if l.Notice && l.Text != "" {
+		if _, err := template.New("").Parse(l.Text); err != nil {
+			return fmt.Errorf("Register: text of license %q: %s", l.ID, err)
+		}
+	}
+
+	licensesMu.Lock()
+	licenses[strings.ToLower(l.ID)] = l
+	licensesMu.Unlock()
+	return nil
+}
+
+// LookupLicense returns the license with the identifier id, if it is
+// registered. The identifier is case insensitive.
+func LookupLicense(id string) (*License, bool) {
+	licensesMu.RLock()
+	defer licensesMu.RUnlock()
+
+	l, ok := licenses[strings.ToLower(id)]
+	return l, ok
+}
+
+// Licenses returns the registered licenses sorted by identifier, being the
+// license "none" the last one.
+func Licenses() []*License {
+	licensesMu.RLock()
+	list := make([]*License, 0, len(licenses))
+	for _, v := range licenses {
+		list = append(list, v)
+	}
+	licensesMu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		a, b := strings.ToLower(list[i].ID), strings.ToLower(list[j].ID)
+		if a == "none" || b == "none" {
+			return b == "none" && a != "none"
+		}
+		return a < b
+	})
+	return list
+}
+
+// Operators of a license expression.
+const (
+	LicenseOr  = "OR"  // any license, at choice of the user
+	LicenseAnd = "AND" // all licenses
+)
+
+// ParseLicense parses a license expression, which is a license identifier or
+// several ones joined by the same operator, as "Apache OR MIT". The operator is
+// case insensitive.
+//
+// It returns the licenses and the operator, which is empty for a single
+// license.
+func ParseLicense(expr string) ([]*License, string, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return nil, "", errors.New("missing license")
+	}
+	if len(fields)%2 == 0 {
+		return nil, "", fmt.Errorf("wrong license expression: %q", expr)
+	}
+
+	list := make([]*License, 0, len(fields)/2+1)
+	op := ""
+
+	for i, v := range fields {
+		if i%2 == 1 {
+			v = strings.ToUpper(v)
+
+			if v != LicenseOr && v != LicenseAnd {
+				return nil, "", fmt.Errorf("wrong operator in license expression: %q", expr)
+			}
+			if op != "" && op != v {
+				return nil, "", fmt.Errorf("mixed operators in license expression: %q", expr)
+			}
+			op = v
+			continue
+		}
+
+		l, ok := LookupLicense(v)
+		if !ok {
+			return nil, "", fmt.Errorf("unavailable license: %q", v)
+		}
+		for _, added := range list {
+			if added == l {
+				return nil, "", fmt.Errorf("duplicated license: %q", v)
+			}
+		}
+		list = append(list, l)
+	}
+
+	if len(list) > 1 {
+		for _, v := range list {
+			if strings.ToLower(v.ID) == "none" {
+				return nil, "", errors.New("license \"none\" can not be combined")
+			}
+		}
+	}
+	return list, op, nil
+}
+
+// spdxExpression returns the SPDX license expression for the licenses joined
+// by the operator.
+func spdxExpression(licenses []*License, op string) string {
+	ids := make([]string, len(licenses))
+	for i, v := range licenses {
+		ids[i] = v.SPDX
+	}
+	return strings.Join(ids, " "+op+" ")
+}
+
+// licenseNames returns the names of the licenses joined by the operator.
+func licenseNames(licenses []*License, op string) string {
+	names := make([]string, len(licenses))
+	for i, v := range licenses {
+		names[i] = v.Name
+	}
+	return strings.Join(names, " "+strings.ToLower(op)+" ")
+}
+
+// File returns the name of the file with the text of the license into the
+// project.
+func (l *License) File() string {
+	return "LICENSE-" + l.ID + ".txt"
+}
+
+// text returns the full text of the license registered, or else the builtin
+// one, got from the data directory.
+func (l *License) text() ([]byte, error) {
+	if l.Text != "" {
+		return []byte(l.Text), nil
+	}
+	return dataFS.ReadFile(path.Join(_DATA_DIR, l.ID+".txt"))
+}
+
+// * * *
+
+func init() {
+	builtin := []*License{
+		{
+			ID:      "AGPL",
+			Name:    "GNU Affero General Public License, version 3 or later",
+			SPDX:    "AGPL-3.0-or-later",
+			Header:  fmt.Sprintf(tmplGNU, "Affero "),
+			Authors: true,
+		},
+		{
+			ID:      "Apache",
+			Name:    "Apache License, version 2.0",
+			SPDX:    "Apache-2.0",
+			Header:  tmplApache,
+			Authors: true,
+		},
+		{
+			ID:      "BSD-2",
+			Name:    "BSD 2-Clause License",
+			SPDX:    "BSD-2-Clause",
+			Header:  tmplBSD,
+			Notice:  true,
+			Authors: true,
+		},
+		{
+			ID:      "BSD-3",
+			Name:    "BSD 3-Clause License",
+			SPDX:    "BSD-3-Clause",
+			Header:  tmplBSD,
+			Notice:  true,
+			Authors: true,
+		},
+		{
+			ID:           "CC0",
+			Name:         "Creative Commons CC0, version 1.0 Universal",
+			SPDX:         "CC0-1.0",
+			Header:       tmplCC0,
+			PublicDomain: true,
+		},
+		{
+			ID:      "EUPL",
+			Name:    "European Union Public Licence, version 1.2 or later",
+			SPDX:    "EUPL-1.2",
+			Header:  tmplEUPL,
+			Authors: true,
+		},
+		{
+			ID:      "GPL",
+			Name:    "GNU General Public License, version 3 or later",
+			SPDX:    "GPL-3.0-or-later",
+			Header:  fmt.Sprintf(tmplGNU, ""),
+			Authors: true,
+		},
+		{
+			ID:      "ISC",
+			Name:    "ISC License",
+			SPDX:    "ISC",
+			Header:  tmplISC,
+			Notice:  true,
+			Authors: true,
+		},
+		{
+			ID:       "LGPL",
+			Name:     "GNU Lesser General Public License, version 3 or later",
+			SPDX:     "LGPL-3.0-or-later",
+			Header:   fmt.Sprintf(tmplGNU, "Lesser "),
+			Requires: []string{"gpl"},
+			Authors:  true,
+		},
+		{
+			ID:      "MIT",
+			Name:    "MIT License",
+			SPDX:    "MIT",
+			Header:  tmplMIT,
+			Notice:  true,
+			Authors: true,
+		},
+		{
+			ID:      "MPL",
+			Name:    "Mozilla Public License, version 2.0",
+			SPDX:    "MPL-2.0",
+			Header:  tmplMPL,
+			Authors: true,
+		},
+		{
+			ID:           "Unlicense",
+			Name:         "The Unlicense",
+			SPDX:         "Unlicense",
+			Header:       tmplUnlicense,
+			PublicDomain: true,
+		},
+		{
+			ID:      "none",
+			Name:    "proprietary license",
+			SPDX:    "LicenseRef-Proprietary",
+			Header:  tmplNone,
+			Authors: true,
+		},
+	}
+
+	for _, v := range builtin {
+		if err := Register(v); err != nil {
+			panic(err)
+		}
+	}
+}
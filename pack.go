@@ -0,0 +1,490 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v1"
+)
+
+// Name of the manifest into the directory of a template pack.
+const _PACK_MANIFEST = "pack.yml"
+
+// Pack represents a template pack, declared by a manifest in YAML. The paths,
+// the conditions "if" and the commands are templates rendered with the
+// configuration (see Conf).
+type Pack struct {
+	Name    string
+	Version string     // version of the templates, recorded into the project
+	Dirs    []string   // directories to create, relative to the project
+	Files   []PackFile // files to create
+	Vars    []PackVar  // extra variables, to pass to templates in Conf.Vars
+	Post    []PackCmd  // commands to run into the project after of creating it
+}
+
+// PackFile represents a file of a template pack.
+type PackFile struct {
+	Path string // destination, relative to the project
+
+	// Source of the file: the name of a builtin template (see the ones parsed
+	// in parseProject), or a file relative to the directory of the pack.
+	Template string
+	Src      string
+	Verbatim bool // the file in Src is copied without rendering it
+
+	When PackCond
+	If   string // included when it is rendered to a value other than "", "false"
+}
+
+// PackVar represents an extra variable to pass to templates, which is asked to
+// the user in interactive mode.
+type PackVar struct {
+	Name     string
+	Prompt   string
+	Type     string   // "string" (by default), "bool", "int" or "choice"
+	Choices  []string // values allowed for the type "choice"
+	Pattern  string   // regular expression to match, for the type "string"
+	Required bool
+	Default  string
+}
+
+// Types of variables of a template pack.
+const (
+	VarString = "string"
+	VarBool   = "bool"
+	VarInt    = "int"
+	VarChoice = "choice"
+)
+
+// Check checks the value of the variable according to its type, returning it
+// normalized (i.e. "true" or "false" for the type "bool").
+func (v *PackVar) Check(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if v.Required {
+			return "", fmt.Errorf("variable %q is required", v.Name)
+		}
+		if v.Type == VarBool {
+			return "false", nil
+		}
+		return "", nil
+	}
+
+	switch v.Type {
+	case VarBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("variable %q: expected boolean: %q", v.Name, value)
+		}
+		return strconv.FormatBool(b), nil
+	case VarInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("variable %q: expected integer: %q", v.Name, value)
+		}
+		return strconv.Itoa(i), nil
+	case VarChoice:
+		for _, c := range v.Choices {
+			if strings.EqualFold(value, c) {
+				return c, nil
+			}
+		}
+		return "", fmt.Errorf("variable %q: unavailable choice: %q", v.Name, value)
+	}
+
+	if v.Pattern != "" {
+		if ok, _ := regexp.MatchString(v.Pattern, value); !ok {
+			return "", fmt.Errorf("variable %q: %q does not match %q", v.Name, value, v.Pattern)
+		}
+	}
+	return value, nil
+}
+
+// PackCmd represents a command to run after of creating the project.
+type PackCmd struct {
+	Run  string // command and its arguments, separated by spaces
+	When PackCond
+	If   string
+}
+
+// PackCond represents the conditions to include an element of the pack. Every
+// list non empty has to match: the license (any one of its expression), VCS,
+// and kind of project.
+type PackCond struct {
+	License []string
+	VCS     []string
+	Kind    []string
+}
+
+// loadPack returns the template pack set in the configuration, or the default
+// one.
+func (c *Conf) loadPack() (*Pack, error) {
+	if c.Pack != "" {
+		return LoadPack(c.Pack)
+	}
+	return parsePack([]byte(defaultPack))
+}
+
+// PackVars returns the variables declared by the template pack set in the
+// configuration.
+func (c *Conf) PackVars() ([]PackVar, error) {
+	pack, err := c.loadPack()
+	if err != nil {
+		return nil, err
+	}
+	return pack.Vars, nil
+}
+
+// LoadPack reads the template pack into the directory dir.
+func LoadPack(dir string) (*Pack, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, _PACK_MANIFEST))
+	if err != nil {
+		return nil, fmt.Errorf("pack error: %s", err)
+	}
+	return parsePack(data)
+}
+
+// parsePack parses and checks the manifest of a template pack.
+func parsePack(data []byte) (*Pack, error) {
+	pack := new(Pack)
+	if err := yaml.Unmarshal(data, pack); err != nil {
+		return nil, fmt.Errorf("error parsing pack: %s", err)
+	}
+
+	for _, v := range pack.Files {
+		switch {
+		case v.Path == "":
+			return nil, errors.New("pack error: file without path")
+		case (v.Template == "") == (v.Src == ""):
+			return nil, fmt.Errorf("pack error: file %q needs either template or src", v.Path)
+		case v.Verbatim && v.Src == "":
+			return nil, fmt.Errorf("pack error: file %q is verbatim without src", v.Path)
+		}
+	}
+	for i := range pack.Vars {
+		v := &pack.Vars[i]
+
+		if v.Name == "" {
+			return nil, errors.New("pack error: variable without name")
+		}
+		if v.Prompt == "" {
+			v.Prompt = v.Name
+		}
+
+		switch v.Type {
+		case "":
+			v.Type = VarString
+		case VarString, VarBool, VarInt:
+		case VarChoice:
+			if len(v.Choices) == 0 {
+				return nil, fmt.Errorf("pack error: variable %q without choices", v.Name)
+			}
+		default:
+			return nil, fmt.Errorf("pack error: variable %q of unknown type: %q", v.Name, v.Type)
+		}
+
+		if v.Pattern != "" {
+			if _, err := regexp.Compile(v.Pattern); err != nil {
+				return nil, fmt.Errorf("pack error: variable %q: %s", v.Name, err)
+			}
+		}
+		if v.Default != "" {
+			if _, err := v.Check(v.Default); err != nil {
+				return nil, fmt.Errorf("pack error: default of %s", err)
+			}
+		}
+	}
+	for _, v := range pack.Post {
+		if strings.TrimSpace(v.Run) == "" {
+			return nil, errors.New("pack error: command without run")
+		}
+	}
+
+	return pack, nil
+}
+
+// * * *
+
+// setVars checks the variables of the pack, setting the value by default to
+// the ones which have not a value.
+func (p *Project) setVars() error {
+	if p.cfg.Vars == nil {
+		p.cfg.Vars = make(map[string]string)
+	}
+
+	for _, v := range p.pack.Vars {
+		value, ok := p.cfg.Vars[v.Name]
+		if !ok {
+			value = v.Default
+		}
+
+		value, err := v.Check(value)
+		if err != nil {
+			return err
+		}
+		p.cfg.Vars[v.Name] = value
+	}
+	return nil
+}
+
+// createFromPack creates the directories and files of the pack into the
+// directory dir.
+func (p *Project) createFromPack(dir string) error {
+	for _, v := range p.pack.Dirs {
+		name, err := p.renderPath(v)
+		if err != nil {
+			return err
+		}
+		if err = p.mkdirAll(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("directory error: %s", err)
+		}
+	}
+
+	for _, v := range p.pack.Files {
+		ok, err := p.include(v.When, v.If)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		name, err := p.renderPath(v.Path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, name)
+
+		var src string
+		if v.Template == "" {
+			if src, err = localPath(v.Src); err != nil {
+				return err
+			}
+			src = filepath.Join(p.packDir, src)
+		}
+
+		if err = p.mkdirAll(filepath.Dir(dst)); err != nil {
+			return fmt.Errorf("directory error: %s", err)
+		}
+
+		switch {
+		case v.Template != "":
+			if p.tmpl.Lookup(v.Template) == nil {
+				return fmt.Errorf("pack error: unknown template: %q", v.Template)
+			}
+			err = p.parseFromVar(dst, v.Template)
+		case v.Verbatim:
+			err = p.copyFile(dst, src)
+		default:
+			err = p.parseFromFile(dst, src)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runPost runs the commands of the pack into the directory dir. Out of the
+// file system of the OS, they are only added to the list of commands not run.
+func (p *Project) runPost(ctx context.Context, dir string) error {
+	for _, v := range p.pack.Post {
+		ok, err := p.include(v.When, v.If)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		run, err := p.render("run", v.Run)
+		if err != nil {
+			return err
+		}
+		args := strings.Fields(run)
+		if len(args) == 0 {
+			continue
+		}
+		if err = p.runCmd(ctx, dir, nil, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// include reports whether an element of the pack has to be included,
+// according to its conditions.
+func (p *Project) include(when PackCond, ifTmpl string) (bool, error) {
+	if len(when.License) != 0 {
+		found := false
+		for _, v := range p.data.Licenses {
+			if inList(v.ID, when.License) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if len(when.VCS) != 0 && !inList(p.cfg.VCS, when.VCS) {
+		return false, nil
+	}
+	if len(when.Kind) != 0 && !inList(p.cfg.Kind, when.Kind) {
+		return false, nil
+	}
+
+	if ifTmpl == "" {
+		return true, nil
+	}
+	value, err := p.render("if", ifTmpl)
+	if err != nil {
+		return false, err
+	}
+	value = strings.TrimSpace(value)
+	return value != "" && value != "false", nil
+}
+
+// renderPath renders the path, given with slashes, and returns it cleaned and
+// with the separator of the system. The path rendered has to be relative, and
+// into the directory where it is joined.
+func (p *Project) renderPath(name string) (string, error) {
+	rendered, err := p.render("path", name)
+	if err != nil {
+		return "", err
+	}
+	return localPath(rendered)
+}
+
+// localPath returns the path name, given with slashes, cleaned and with the
+// separator of the system. It returns an error if the path is absolute or it
+// is out of the directory where it is joined.
+func localPath(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" ||
+		clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("pack error: path out of the directory: %q", name)
+	}
+	return clean, nil
+}
+
+// render renders the text of a template named name, with the configuration.
+func (p *Project) render(name, text string) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing error in %s %q: %s", name, text, err)
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, p.data); err != nil {
+		return "", fmt.Errorf("execution failed in %s %q: %s", name, text, err)
+	}
+	return buf.String(), nil
+}
+
+// inList reports whether the value is in the list, in a case insensitive way.
+func inList(value string, list []string) bool {
+	for _, v := range list {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// * * *
+
+// Manifest of the default pack, which creates the projects by kind using the
+// builtin templates.
+const defaultPack = `
+name: default
+version: "1"
+
+dirs:
+  - doc
+  - testdata
+
+files:
+  - path: go.mod
+    template: GoMod
+
+  # Library
+  - path: "{{.Program}}.go"
+    template: Go
+    when: {kind: [lib, libcmd]}
+  - path: "_{{.Program}}_test.go"
+    template: Test
+    when: {kind: [lib, libcmd]}
+  - path: _example_test.go
+    template: Example
+    when: {kind: [lib, libcmd]}
+
+  # Command
+  - path: main.go
+    template: Cmd
+    when: {kind: [cmd]}
+  - path: doc.go
+    template: CmdDoc
+    when: {kind: [cmd]}
+  - path: "cmd/{{.Program}}/main.go"
+    template: Cmd
+    when: {kind: [libcmd]}
+  - path: "cmd/{{.Program}}/doc.go"
+    template: CmdDoc
+    when: {kind: [libcmd]}
+
+  # Web service
+  - path: "cmd/{{.Program}}/main.go"
+    template: SvcMain
+    when: {kind: [svc]}
+  - path: internal/handler/handler.go
+    template: SvcHandler
+    when: {kind: [svc]}
+  - path: internal/handler/handler_test.go
+    template: SvcHandlerTest
+    when: {kind: [svc]}
+  - path: internal/config/config.go
+    template: SvcConfig
+    when: {kind: [svc]}
+  - path: Dockerfile
+    template: SvcDockerfile
+    when: {kind: [svc]}
+
+  # Information files
+  - path: README.md
+    template: Readme
+  - path: CONTRIBUTORS.txt.md
+    template: Contributors
+  - path: doc/_changelog.txt.md
+    template: Changelog
+  # It is for copyright holders.
+  - path: AUTHORS.txt.md
+    template: Authors
+    if: "{{.AuthorsFile}}"
+
+  # VCS
+  - path: ".{{.VCS}}ignore"
+    template: Ignore
+    when: {vcs: [bzr, git, hg]}
+
+post:
+  - run: "{{.VCS}} init"
+    when: {vcs: [bzr, git, hg]}
+`
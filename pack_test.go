@@ -0,0 +1,62 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		clean string
+		ok    bool
+	}{
+		{"foo.go", "foo.go", true},
+		{"cmd/foo/main.go", "cmd/foo/main.go", true},
+		{"./cmd//foo/../bar.go", "cmd/bar.go", true},
+		{"..foo/bar.go", "..foo/bar.go", true},
+		{"..", "", false},
+		{"../foo.go", "", false},
+		{"../../.bashrc", "", false},
+		{"cmd/../../foo.go", "", false},
+		{"/etc/passwd", "", false},
+	}
+
+	for _, tt := range tests {
+		clean, err := localPath(tt.name)
+		if (err == nil) != tt.ok {
+			t.Errorf("localPath(%q): got error %v, want ok %v", tt.name, err, tt.ok)
+			continue
+		}
+		if clean != filepath.FromSlash(tt.clean) {
+			t.Errorf("localPath(%q) = %q, want %q", tt.name, clean, tt.clean)
+		}
+	}
+}
+
+func TestCreateFromPackOutOfDir(t *testing.T) {
+	for _, pack := range []*Pack{
+		{Dirs: []string{"../{{.Program}}"}},
+		{Files: []PackFile{{Path: "../../.bashrc", Template: "Go"}}},
+		{Files: []PackFile{{Path: "foo.go", Src: "../../.bashrc"}}},
+	} {
+		fs := NewMemFS()
+		p, err := New(testConf("lib"), &Options{FS: fs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.pack = pack
+
+		err = p.createFromPack("foo")
+		if err == nil || !strings.Contains(err.Error(), "out of the directory") {
+			t.Errorf("pack %+v: got error %v, want path out of the directory", pack, err)
+		}
+	}
+}
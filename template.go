@@ -7,13 +7,20 @@
 package wizard
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
 	"time"
 )
 
+// Extension of the files in the user directory of templates which override
+// the builtin templates.
+const _TMPL_EXT = ".tmpl"
+
 // Copyright
 const (
 	tmplCopyright = `Copyright {{.Year}} {{.Author}}`
@@ -23,6 +30,11 @@ const (
 	tmplOrgCopyleft  = `Written in {{.Year}} by the {{.Project}} Authors`
 )
 
+// Header with the delimiters of a block comment, if any.
+const tmplHeader = `{{with .CommentStart}}{{.}}
+{{end}}{{template "LicenseHeader" .}}{{with .CommentEnd}}{{.}}
+{{end}}`
+
 // Licenses
 const (
 	tmplNone = `{{.Comment}} {{template "Copyright" .}}
@@ -43,19 +55,20 @@ const (
 {{.Comment}} limitations under the License.
 `
 
+	// To format with the extra word in the name of the GNU license, if any.
 	tmplGNU = `{{.Comment}} {{template "Copyright" .}}
 {{.Comment}}
 {{.Comment}} This program is free software: you can redistribute it and/or modify
-{{.Comment}} it under the terms of the GNU {{with .GNUextra}}{{.}} {{end}}General Public License as published by
+{{.Comment}} it under the terms of the GNU %[1]sGeneral Public License as published by
 {{.Comment}} the Free Software Foundation, either version 3 of the License, or
 {{.Comment}} (at your option) any later version.
 {{.Comment}}
 {{.Comment}} This program is distributed in the hope that it will be useful,
 {{.Comment}} but WITHOUT ANY WARRANTY; without even the implied warranty of
 {{.Comment}} MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
-{{.Comment}} GNU {{with .GNUextra}}{{.}} {{end}}General Public License for more details.
+{{.Comment}} GNU %[1]sGeneral Public License for more details.
 {{.Comment}}
-{{.Comment}} You should have received a copy of the GNU {{with .GNUextra}}{{.}} {{end}}General Public License
+{{.Comment}} You should have received a copy of the GNU %[1]sGeneral Public License
 {{.Comment}} along with this program.  If not, see <http://www.gnu.org/licenses/>.
 `
 
@@ -74,6 +87,65 @@ const (
 {{.Comment}}
 {{.Comment}} You should have received a copy of the CC0 Public Domain Dedication along
 {{.Comment}} with this software. If not, see <http://creativecommons.org/publicdomain/zero/1.0/>.
+`
+
+	tmplBSD = `{{.Comment}} {{template "Copyright" .}}
+{{.Comment}}
+{{.Comment}} Use of this source code is governed by a BSD-style
+{{.Comment}} license that can be found in the {{.LicenseFile}} file.
+`
+
+	tmplMIT = `{{.Comment}} {{template "Copyright" .}}
+{{.Comment}}
+{{.Comment}} Use of this source code is governed by an MIT-style
+{{.Comment}} license that can be found in the {{.LicenseFile}} file.
+`
+
+	tmplISC = `{{.Comment}} {{template "Copyright" .}}
+{{.Comment}}
+{{.Comment}} Use of this source code is governed by an ISC-style
+{{.Comment}} license that can be found in the {{.LicenseFile}} file.
+`
+
+	tmplEUPL = `{{.Comment}} {{template "Copyright" .}}
+{{.Comment}}
+{{.Comment}} Licensed under the EUPL, Version 1.2 or - as soon they will be approved by
+{{.Comment}} the European Commission - subsequent versions of the EUPL (the "Licence");
+{{.Comment}} You may not use this work except in compliance with the Licence.
+{{.Comment}} You may obtain a copy of the Licence at:
+{{.Comment}}
+{{.Comment}}     https://joinup.ec.europa.eu/software/page/eupl
+{{.Comment}}
+{{.Comment}} Unless required by applicable law or agreed to in writing, software
+{{.Comment}} distributed under the Licence is distributed on an "AS IS" basis,
+{{.Comment}} WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+{{.Comment}} See the Licence for the specific language governing permissions and
+{{.Comment}} limitations under the Licence.
+`
+
+	tmplUnlicense = `{{.Comment}} {{template "Copyright" .}}
+{{.Comment}}
+{{.Comment}} This is free and unencumbered software released into the public domain.
+{{.Comment}} For more information, please refer to <http://unlicense.org/>
+`
+
+	// Several licenses, listed by the template "LicenseList".
+	tmplMulti = `{{.Comment}} {{template "Copyright" .}}
+{{.Comment}}
+{{.Comment}} Licensed under {{if eq .LicenseOp "OR"}}any{{else}}all{{end}} of the following licenses{{if eq .LicenseOp "OR"}}, at your option{{end}}:
+{{.Comment}}
+{{template "LicenseList" .}}`
+
+	tmplLicenseList = `{{range .Licenses}}{{$.Comment}}   + {{.Name}}, in file {{.File}}
+{{end}}`
+
+	tmplSPDX = `{{.Comment}} {{template "Copyright" .}}
+{{.Comment}} SPDX-License-Identifier: {{.SPDXID}}
+`
+
+	// To be added after the license text.
+	tmplSPDXextra = `{{.Comment}}
+{{.Comment}} SPDX-License-Identifier: {{.SPDXID}}
 `
 )
 
@@ -94,7 +166,7 @@ package {{.Program}}
 
 import "testing"
 
-func Test(t *testing.T) {
+func Test{{.FuncName}}(t *testing.T) {
 	
 }
 `
@@ -102,37 +174,301 @@ func Test(t *testing.T) {
 	tmplExample = `{{template "Header" .}}
 package {{.Program}}_test
 
+import "fmt"
+
+func Example{{.FuncName}}() {
+	fmt.Println()
+	// Output:
+	// 
+}
+`
+)
+
+// Base of commands
+const (
+	tmplCmd = `{{template "Header" .}}
+package main
+
 import (
+	"flag"
 	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: {{.Program}} [flags]\n\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+}
+`
+
+	tmplCmdDoc = `{{template "Header" .}}
+/*
+Command {{.Program}} << COMMAND SYNOPSIS >>
 
-	"{{.ImportPath}}"
+Usage:
+
+	{{.Program}} [flags]
+*/
+package main
+`
 )
 
-func Example() {
-	fmt.Println()
-	// Output:
-	// 
+// Base of web services
+const (
+	tmplSvcMain = `{{template "Header" .}}
+// Command {{.Program}} << SERVICE SYNOPSIS >>
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"{{.ModulePath}}/internal/config"
+	"{{.ModulePath}}/internal/handler"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	h := handler.New()
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", cfg.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+	h.SetReady(true)
+
+	select {
+	case err = <-errc:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	// Graceful shutdown, waiting for the active requests.
+	log.Print("shutting down")
+	h.SetReady(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err = srv.Shutdown(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+`
+
+	tmplSvcHandler = `{{template "Header" .}}
+// Package handler implements the HTTP handlers of the service.
+package handler
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Handler routes the requests of the service.
+type Handler struct {
+	mux   *http.ServeMux
+	ready int32 // 1 if it is ready; accessed atomically
+}
+
+// New returns the handler of the service, which is not ready until it is
+// called SetReady.
+func New() *Handler {
+	h := &Handler{mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("/healthz", h.healthz)
+	h.mux.HandleFunc("/readyz", h.readyz)
+	return h
+}
+
+// SetReady sets whether the service is ready to serve requests.
+func (h *Handler) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&h.ready, v)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// healthz reports that the service is alive.
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// readyz reports whether the service is ready to serve requests.
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&h.ready) == 0 {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+`
+
+	tmplSvcHandlerTest = `{{template "Header" .}}
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status: got %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := New()
+
+	for _, tt := range []struct {
+		ready bool
+		code  int
+	}{
+		{false, http.StatusServiceUnavailable},
+		{true, http.StatusOK},
+		{false, http.StatusServiceUnavailable},
+	} {
+		h.SetReady(tt.ready)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+		if rec.Code != tt.code {
+			t.Errorf("ready=%v: got status %d, want %d", tt.ready, rec.Code, tt.code)
+		}
+	}
+}
+`
+
+	tmplSvcConfig = `{{template "Header" .}}
+// Package config loads the configuration of the service from the environment.
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Config represents the configuration of the service.
+type Config struct {
+	Addr            string        // TCP address to listen on
+	ShutdownTimeout time.Duration // time to wait for the active requests at shutting down
+}
+
+// Load returns the configuration got from the environment variables
+// {{.EnvPrefix}}_ADDR and {{.EnvPrefix}}_SHUTDOWN_TIMEOUT, or the values by
+// default for the ones not set.
+func Load() (*Config, error) {
+	c := &Config{
+		Addr:            ":8080",
+		ShutdownTimeout: 10 * time.Second,
+	}
+
+	if v := os.Getenv("{{.EnvPrefix}}_ADDR"); v != "" {
+		c.Addr = v
+	}
+	if v := os.Getenv("{{.EnvPrefix}}_SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("config: {{.EnvPrefix}}_SHUTDOWN_TIMEOUT: %s", err)
+		}
+		c.ShutdownTimeout = d
+	}
+
+	return c, nil
 }
+`
+
+	tmplSvcDockerfile = `{{template "Header" .}}
+FROM golang:{{.GoVersion}} AS build
+
+WORKDIR /src
+COPY go.mod go.sum* ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -o /{{.Program}} ./cmd/{{.Program}}
+
+FROM gcr.io/distroless/static-debian12
+
+COPY --from=build /{{.Program}} /{{.Program}}
+EXPOSE 8080
+USER nonroot:nonroot
+ENTRYPOINT ["/{{.Program}}"]
 `
 )
 
+// Module definition
+const tmplGoMod = `module {{.ModulePath}}
+
+go {{.GoVersion}}
+{{with .Toolchain}}
+toolchain {{.}}
+{{end}}`
+
 // User configuration
 const tmplUserConfig = `
 org: {{.Org}}
 author: {{.Author}}
 email: {{.Email}}
 license: {{.License}}
+spdx: {{.SPDX}}
 vcs: {{.VCS}}
-import: {{.ImportPath}}
-`
+{{with .Kind}}kind: {{.}}
+{{end}}import: {{.ImportPath}}
+{{with .DocLicense}}doclicense: {{.}}
+{{end}}{{with .GoVersion}}go: {{.}}
+{{end}}{{with .Toolchain}}toolchain: {{.}}
+{{end}}{{with .DataDir}}data: {{.}}
+{{end}}{{with .TemplateDir}}templates: {{.}}
+{{end}}{{with .Pack}}pack: {{.}}
+{{end}}{{if .Commit}}commit: true
+{{end}}{{with .CommitMsg}}commitmsg: {{printf "%q" .}}
+{{end}}`
 
 // Ignore file for VCS
 const hgIgnoreTop = "syntax: glob\n"
 
-var tmplIgnore = `## Special files
+const tmplIgnore = `## Special files
 *~
 [._]*
-
+{{if eq .VCS "git"}}!/.gowizard.yml
+{{else if eq .VCS "bzr"}}!./.gowizard.yml
+{{end}}
 # Compiled Object files, Static and Dynamic libs (Shared Objects)
 *.[ao]
 *.so
@@ -180,7 +516,7 @@ _testmain.go
 Icon?
 
 # * * *
-{{.Program}}
+{{if or (eq .Kind "libcmd") (eq .Kind "svc")}}cmd/{{.Program}}/{{end}}{{.Program}}
 `
 
 // Information files
@@ -239,85 +575,205 @@ Items should be added to this file as:
 {{.ProjectHeader}}
 << PROJECT SYNOPSIS >>
 
-[Documentation online](http://godoc.org/{{with .ImportPath}}{{.}}{{else}}<< IMPORT PATH >>{{end}})
+[Documentation online](https://pkg.go.dev/{{with .ImportPath}}{{.}}{{else}}<< IMPORT PATH >>{{end}})
 
 ## Installation
+{{if eq .Kind "svc"}}
+To run the service:
 
-	go get {{with .ImportPath}}{{.}}{{else}}<< IMPORT PATH >>{{end}}
-{{if .FullLicense}}
-## License
+	go run ./cmd/{{.Program}}
 
-Unless otherwise noted:
+or into a container:
 
-+ The source files are distributed under the *{{.FullLicense}}*
-{{end}}
+	docker build -t {{.Program}} .
+	docker run -p 8080:8080 {{.Program}}
+{{else}}{{if ne .Kind "cmd"}}
+To add it as dependency of your module:
+
+	go get {{with .ImportPath}}{{.}}{{else}}<< IMPORT PATH >>{{end}}@latest
+{{end}}{{if ne .Kind "lib"}}
+To install the command:
+
+	go install {{with .ImportPath}}{{.}}{{else}}<< IMPORT PATH >>{{end}}{{if eq .Kind "libcmd"}}/cmd/{{.Program}}{{end}}@latest
+{{end}}{{end}}{{template "ReadmeLicense" .}}
 * * *
 *Generated by [Gowizard](https://github.com/tredoe/wizard)*
 `
+
+	tmplReadmeLicense = `{{if or .FullLicense .DocLicenses}}
+## License
+
+Unless otherwise noted:
+
+{{if .FullLicense}}+ The source files are distributed under the {{range $i, $v := .Licenses}}{{if $i}} {{if eq $.LicenseOp "OR"}}or{{else}}and{{end}} the {{end}}*{{$v.Name}}*{{end}}{{if eq .LicenseOp "OR"}}, at your option{{end}}
+{{end}}{{with .DocLicenses}}+ The documentation is distributed under the {{range $i, $v := .}}{{if $i}} {{if eq $.DocLicenseOp "OR"}}or{{else}}and{{end}} the {{end}}*{{$v.Name}}*{{end}}{{if eq $.DocLicenseOp "OR"}}, at your option{{end}}
+{{end}}{{end}}`
 )
 
 // * * *
 
 // parseFromFile renders the template "src", creating a file in "dst".
-func (p *project) parseFromFile(dst, src string) error {
-	file, err := createFile(dst)
+// The license header is commented according to the extension of "dst".
+func (p *Project) parseFromFile(dst, src string) (err error) {
+	p.tmpl, err = p.tmpl.ParseFiles(src)
 	if err != nil {
-		return err
+		return fmt.Errorf("parsing error: %s", err)
 	}
+	return p.execute(dst, filepath.Base(src))
+}
 
-	p.tmpl, err = p.tmpl.ParseFiles(src)
+// parseTemplateDir parses the templates into the user directory of templates.
+//
+// The files at the top of the directory named as a template plus extension
+// ".tmpl" (i.e. "Readme.tmpl") override the builtin template. Returns the
+// rest of files, to be added to the project.
+func (p *Project) parseTemplateDir() ([]string, error) {
+	files := make([]string, 0)
+
+	err := filepath.Walk(p.tmplDir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// Metadata of version control systems.
+			if _, ok := ListVCS[strings.TrimPrefix(info.Name(), ".")]; ok &&
+				strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if filepath.Dir(name) != filepath.Clean(p.tmplDir) ||
+			filepath.Ext(name) != _TMPL_EXT {
+			files = append(files, name)
+			return nil
+		}
+
+		tmplName := strings.TrimSuffix(filepath.Base(name), _TMPL_EXT)
+		if p.tmpl.Lookup(tmplName) == nil {
+			return fmt.Errorf("unknown template: %q", tmplName)
+		}
+
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if _, err = p.tmpl.New(tmplName).Parse(string(src)); err != nil {
+			return fmt.Errorf("parsing error: %s", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("parsing error: %s", err)
+		return nil, fmt.Errorf("template directory error: %s", err)
 	}
-	if err = p.tmpl.ExecuteTemplate(file, filepath.Base(src), p.cfg); err != nil {
-		return fmt.Errorf("execution failed: %s", err)
+
+	return files, nil
+}
+
+// addFromTemplateDir renders the files got from the user directory of
+// templates into the directory dir. The path of every file, relative to the
+// directory of templates, is a template too (i.e. "{{.Program}}_util.go").
+func (p *Project) addFromTemplateDir(dir string, files []string) error {
+	for _, src := range files {
+		rel, err := filepath.Rel(p.tmplDir, src)
+		if err != nil {
+			return err
+		}
+
+		name, err := p.renderPath(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(dir, name)
+		if err = p.mkdirAll(filepath.Dir(dst)); err != nil {
+			return fmt.Errorf("directory error: %s", err)
+		}
+		if err = p.parseFromFile(dst, src); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 // parseFromVar renders the template "tmplName" to the file "dst".
-func (p *project) parseFromVar(dst string, tmplName string) error {
-	file, err := createFile(dst)
-	if err != nil {
-		return err
+// The license header is commented according to the extension of "dst".
+func (p *Project) parseFromVar(dst string, tmplName string) error {
+	return p.execute(dst, tmplName)
+}
+
+// execute renders the template "tmplName" to the file "dst", commenting the
+// license header according to the extension of "dst".
+func (p *Project) execute(dst string, tmplName string) error {
+	style, ok := commentStyleFor(dst)
+	if !ok {
+		style = ListCommentStyle[".go"]
 	}
+	p.setComment(style)
 
-	if err = p.tmpl.ExecuteTemplate(file, tmplName, p.cfg); err != nil {
+	var buf bytes.Buffer
+	if err := p.tmpl.ExecuteTemplate(&buf, tmplName, p.data); err != nil {
 		return fmt.Errorf("execution failed: %s", err)
 	}
+
+	if err := p.writeFile(dst, buf.Bytes(), _FILE_PERM); err != nil {
+		return fmt.Errorf("file error: %s", err)
+	}
 	return nil
 }
 
-// parseLicense parses the license header.
-// charComment is the character used to comment in code files.
-func (p *project) parseLicense(charComment string) {
-	licenseName := strings.Split(p.cfg.License, "-")[0]
-	tmplHeader := ""
-
-	p.cfg.Comment = charComment
-	p.cfg.Year = time.Now().Year()
-
-	switch licenseName {
-	case "mpl":
-		tmplHeader = tmplMPL
-	case "apache":
-		tmplHeader = tmplApache
-	case "cc0":
-		tmplHeader = tmplCC0
-	case "gpl", "agpl":
-		tmplHeader = tmplGNU
-
-		if licenseName == "agpl" {
-			p.cfg.GNUextra = "Affero"
-		}
-	case "none":
-		tmplHeader = tmplNone
+// parseLicense parses the license header, and sets the fields about licenses
+// to pass to templates. The comment style is set at rendering the header.
+func (p *Project) parseLicense() {
+	licenses, op := p.cfg.licenses()
+	p.data.DocLicenses, p.data.DocLicenseOp = p.cfg.docLicenses()
+
+	p.data.FullLicense = ""
+	if p.cfg.License != "none" {
+		p.data.FullLicense = licenseNames(licenses, op)
+	}
+	p.data.AuthorsFile = p.needAuthors()
+
+	p.parseHeader(licenses, op)
+}
+
+// parseHeader parses the header for the licenses joined by the operator.
+func (p *Project) parseHeader(licenses []*License, op string) {
+	tmplLicense := tmplMulti
+	publicDomain := true
+
+	p.data.Licenses, p.data.LicenseOp = licenses, op
+	p.data.SPDXID = spdxExpression(licenses, op)
+	p.data.LicenseFile = ""
+	p.data.Year = p.year
+	if p.data.Year == 0 {
+		p.data.Year = time.Now().Year()
+	}
+
+	if len(licenses) == 1 {
+		tmplLicense = licenses[0].Header
+		p.data.LicenseFile = licenses[0].File()
+	}
+	for _, v := range licenses {
+		publicDomain = publicDomain && v.PublicDomain
+	}
+
+	switch p.cfg.SPDX {
+	case SPDXOnly:
+		tmplLicense = tmplSPDX
+	case SPDXAdd:
+		tmplLicense += tmplSPDXextra
 	}
 
 	p.tmpl = template.Must(p.tmpl.New("Header").Parse(tmplHeader))
+	p.tmpl = template.Must(p.tmpl.New("LicenseHeader").Parse(tmplLicense))
+	p.tmpl = template.Must(p.tmpl.New("LicenseList").Parse(tmplLicenseList))
 
-	if licenseName != "cc0" {
+	if !publicDomain {
 		if p.cfg.Org == "" {
 			p.tmpl = template.Must(p.tmpl.New("Copyright").Parse(tmplCopyright))
 		} else {
@@ -333,18 +789,28 @@ func (p *project) parseLicense(charComment string) {
 }
 
 // parseProject parses the templates for the project.
-func (p *project) parseProject() {
+func (p *Project) parseProject() {
 	p.tmpl = template.Must(p.tmpl.New("Authors").Parse(tmplAuthors))
 	p.tmpl = template.Must(p.tmpl.New("Contributors").Parse(tmplContributors))
 	p.tmpl = template.Must(p.tmpl.New("Changelog").Parse(tmplChangelog))
 	p.tmpl = template.Must(p.tmpl.New("Readme").Parse(tmplReadme))
+	p.tmpl = template.Must(p.tmpl.New("ReadmeLicense").Parse(tmplReadmeLicense))
 	p.tmpl = template.Must(p.tmpl.New("Go").Parse(tmplGo))
 	p.tmpl = template.Must(p.tmpl.New("Test").Parse(tmplTest))
 	p.tmpl = template.Must(p.tmpl.New("Example").Parse(tmplExample))
+	p.tmpl = template.Must(p.tmpl.New("Cmd").Parse(tmplCmd))
+	p.tmpl = template.Must(p.tmpl.New("CmdDoc").Parse(tmplCmdDoc))
+	p.tmpl = template.Must(p.tmpl.New("SvcMain").Parse(tmplSvcMain))
+	p.tmpl = template.Must(p.tmpl.New("SvcHandler").Parse(tmplSvcHandler))
+	p.tmpl = template.Must(p.tmpl.New("SvcHandlerTest").Parse(tmplSvcHandlerTest))
+	p.tmpl = template.Must(p.tmpl.New("SvcConfig").Parse(tmplSvcConfig))
+	p.tmpl = template.Must(p.tmpl.New("SvcDockerfile").Parse(tmplSvcDockerfile))
+	p.tmpl = template.Must(p.tmpl.New("GoMod").Parse(tmplGoMod))
 
 	// == Ignore file
+	ignore := tmplIgnore
 	if p.cfg.VCS == "hg" {
-		tmplIgnore = hgIgnoreTop + tmplIgnore
+		ignore = hgIgnoreTop + ignore
 	}
-	p.tmpl = template.Must(p.tmpl.New("Ignore").Parse(tmplIgnore))
+	p.tmpl = template.Must(p.tmpl.New("Ignore").Parse(ignore))
 }
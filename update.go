@@ -0,0 +1,440 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v1"
+)
+
+// Name of the file which records how the project was created.
+const _META_FILE = ".gowizard.yml"
+
+// Directory with the copies of the output rendered at creating or updating the
+// project, used as base to merge the changes. It has not to be committed, so
+// the ignore file rendered for the VCS skips it but not the metadata file.
+// Mercurial has not exceptions in the ignore file, so that file has to be added
+// explicitly.
+const _BASE_DIR = ".gowizard"
+
+// Markers of a conflict at merging the changes of the templates.
+const (
+	_CONFLICT_START = "<<<<<<< project\n"
+	_CONFLICT_SEP   = "=======\n"
+	_CONFLICT_END   = ">>>>>>> templates\n"
+)
+
+// Meta represents the metadata of a project, recorded at creating it to can
+// update it when the templates change.
+type Meta struct {
+	Pack    string            // name of the template pack
+	Version string            // version of the template pack
+	Year    int               // year of the copyright
+	Conf    *MetaConf         `yaml:"config"`
+	Files   map[string]string // hash of the output rendered, by path relative to the project
+}
+
+// MetaConf represents the configuration of the project recorded into its
+// metadata. The settings of the user, as the email and the directories of
+// custom license texts, templates and template pack, are not recorded.
+type MetaConf struct {
+	Project     string
+	Program     string
+	License     string
+	DocLicense  string `yaml:",omitempty"`
+	SPDX        string `yaml:",omitempty"`
+	Author      string `yaml:",omitempty"`
+	Org         string `yaml:",omitempty"`
+	VCS         string
+	Kind        string
+	ImportPaths []string          `yaml:",omitempty"`
+	Vars        map[string]string `yaml:",omitempty"`
+	GoVersion   string            `yaml:"go"`
+	Toolchain   string            `yaml:",omitempty"`
+}
+
+// newMetaConf returns the configuration to record from cfg.
+func newMetaConf(cfg *Conf) *MetaConf {
+	return &MetaConf{
+		Project:     cfg.Project,
+		Program:     cfg.Program,
+		License:     cfg.License,
+		DocLicense:  cfg.DocLicense,
+		SPDX:        cfg.SPDX,
+		Author:      cfg.Author,
+		Org:         cfg.Org,
+		VCS:         cfg.VCS,
+		Kind:        cfg.Kind,
+		ImportPaths: cfg.ImportPaths,
+		Vars:        cfg.Vars,
+		GoVersion:   cfg.GoVersion,
+		Toolchain:   cfg.Toolchain,
+	}
+}
+
+// conf returns the configuration recorded, with the settings of the user got
+// from user, if any.
+func (m *MetaConf) conf(user *Conf) *Conf {
+	cfg := &Conf{
+		Project:     m.Project,
+		Program:     m.Program,
+		License:     m.License,
+		DocLicense:  m.DocLicense,
+		SPDX:        m.SPDX,
+		Author:      m.Author,
+		Org:         m.Org,
+		VCS:         m.VCS,
+		Kind:        m.Kind,
+		ImportPaths: m.ImportPaths,
+		Vars:        m.Vars,
+		GoVersion:   m.GoVersion,
+		Toolchain:   m.Toolchain,
+	}
+	if user != nil {
+		cfg.Email = user.Email
+		cfg.DataDir = user.DataDir
+		cfg.TemplateDir = user.TemplateDir
+		cfg.Pack = user.Pack
+	}
+	return cfg
+}
+
+// writeMeta writes the metadata of the project into the directory dir, with
+// the hashes of the files written into it, and their copies into the base
+// directory.
+func (p *Project) writeMeta(dir string) error {
+	meta := &Meta{
+		Pack:    p.pack.Name,
+		Version: p.pack.Version,
+		Year:    p.data.Year,
+		Conf:    newMetaConf(p.cfg),
+		Files:   make(map[string]string, len(p.written)),
+	}
+	for name, data := range p.written {
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return fmt.Errorf("metadata error: %s", err)
+		}
+		meta.Files[filepath.ToSlash(rel)] = hashOf(data)
+
+		base := filepath.Join(dir, _BASE_DIR, rel)
+		if err = p.mkdirAll(filepath.Dir(base)); err != nil {
+			return fmt.Errorf("metadata error: %s", err)
+		}
+		if err = p.fs.WriteFile(base, data, _FILE_PERM); err != nil {
+			return fmt.Errorf("metadata error: %s", err)
+		}
+	}
+
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("metadata error: %s", err)
+	}
+	return p.fs.WriteFile(filepath.Join(dir, _META_FILE), data, _FILE_PERM)
+}
+
+// parseMeta parses the metadata of a project.
+func parseMeta(data []byte) (*Meta, error) {
+	meta := new(Meta)
+	if err := yaml.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("error parsing metadata: %s", err)
+	}
+	if meta.Conf == nil {
+		return nil, errors.New("metadata error: configuration not found")
+	}
+	return meta, nil
+}
+
+// Update renders again the project into the directory dir, with the
+// configuration recorded at creating it, and merges the changes of the
+// templates into its files, keeping the changes done to them. It returns the
+// files updated, and the ones with conflicts, which are marked like in the VCS.
+//
+// The settings of the user, which are not recorded, are got from cfg, if any;
+// the template pack has to be the one used to create the project, although its
+// version can be another one. The files removed from the project are not
+// created again. Without the copy of a file in the base directory, as in a
+// clone of the repository, the changes done at both sides of that file are
+// marked as a conflict.
+func Update(dir string, cfg *Conf) (updated, conflicts []string, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, _META_FILE))
+	if err != nil {
+		return nil, nil, fmt.Errorf("metadata error: %s", err)
+	}
+	old, err := parseMeta(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fs := NewMemFS()
+	p, err := New(old.Conf.conf(cfg), &Options{FS: fs})
+	if err != nil {
+		return nil, nil, err
+	}
+	p.year = old.Year
+
+	if p.pack.Name != old.Pack {
+		return nil, nil, fmt.Errorf("update error: the project was created with the template pack %q, not %q",
+			old.Pack, p.pack.Name)
+	}
+
+	if err = p.Create(); err != nil {
+		return nil, nil, err
+	}
+	root := p.fsRoot()
+
+	if data, err = fs.ReadFile(path.Join(root, _META_FILE)); err != nil {
+		return nil, nil, err
+	}
+	meta, err := parseMeta(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(meta.Files))
+	for k := range meta.Files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		src, err := fs.ReadFile(path.Join(root, name))
+		if err != nil {
+			return updated, conflicts, err
+		}
+		theirs := string(src)
+		file := filepath.Join(dir, filepath.FromSlash(name))
+
+		baseHash, inBase := old.Files[name]
+		if baseHash == meta.Files[name] {
+			continue
+		}
+		perm := os.FileMode(_FILE_PERM)
+
+		var base, ours string
+		switch info, err := os.Stat(file); {
+		case os.IsNotExist(err):
+			if inBase {
+				continue
+			}
+		case err != nil:
+			return updated, conflicts, fmt.Errorf("update error: %s", err)
+		default:
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				return updated, conflicts, fmt.Errorf("update error: %s", err)
+			}
+			ours, perm = string(src), info.Mode().Perm()
+
+			if ours == theirs {
+				continue
+			}
+			if hashOf(src) == baseHash {
+				base = ours
+			} else if base, err = readBase(dir, name, baseHash); err != nil {
+				return updated, conflicts, fmt.Errorf("update error: %s", err)
+			}
+		}
+
+		merged, ok := merge3(base, ours, theirs)
+
+		if err = writeFileAll(file, []byte(merged), perm); err != nil {
+			return updated, conflicts, fmt.Errorf("update error: %s", err)
+		}
+
+		if ok {
+			updated = append(updated, name)
+		} else {
+			conflicts = append(conflicts, name)
+		}
+	}
+
+	// The new base is the output rendered now.
+	baseDir := filepath.Join(dir, _BASE_DIR)
+	if err = os.RemoveAll(baseDir); err != nil {
+		return updated, conflicts, fmt.Errorf("metadata error: %s", err)
+	}
+	for _, name := range names {
+		src, err := fs.ReadFile(path.Join(root, name))
+		if err != nil {
+			return updated, conflicts, err
+		}
+		if err = writeFileAll(filepath.Join(baseDir, filepath.FromSlash(name)), src, _FILE_PERM); err != nil {
+			return updated, conflicts, fmt.Errorf("metadata error: %s", err)
+		}
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, _META_FILE), data, _FILE_PERM); err != nil {
+		return updated, conflicts, fmt.Errorf("metadata error: %s", err)
+	}
+	return updated, conflicts, nil
+}
+
+// readBase returns the copy of the file name, relative to the project into the
+// directory dir, rendered at creating or updating the project. It returns an
+// empty string if the copy does not exist or it does not match the hash.
+func readBase(dir, name, hash string) (string, error) {
+	src, err := ioutil.ReadFile(filepath.Join(dir, _BASE_DIR, filepath.FromSlash(name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if hashOf(src) != hash {
+		return "", nil
+	}
+	return string(src), nil
+}
+
+// writeFileAll writes data to the file name, creating its directory if it is
+// necessary.
+func writeFileAll(name string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), _DIR_PERM); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, data, perm)
+}
+
+// hashOf returns the hash SHA-256 of data, in hexadecimal.
+func hashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// == Merge
+//
+
+// merge3 merges by lines the changes from base to ours, and from base to
+// theirs. It returns false if there are conflicts, which are marked into the
+// text merged.
+func merge3(base, ours, theirs string) (string, bool) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchO, matchT := matchLines(b, o), matchLines(b, t)
+
+	var buf bytes.Buffer
+	clean := true
+
+	for i, x, y := 0, 0, 0; ; {
+		// Next line of base kept in both versions.
+		j := i
+		for j < len(b) && (matchO[j] == -1 || matchT[j] == -1) {
+			j++
+		}
+		endX, endY := len(o), len(t)
+		if j < len(b) {
+			endX, endY = matchO[j], matchT[j]
+		}
+
+		chunkB, chunkO, chunkT := b[i:j], o[x:endX], t[y:endY]
+
+		switch {
+		case equalLines(chunkO, chunkB):
+			writeLines(&buf, chunkT, false)
+		case equalLines(chunkT, chunkB), equalLines(chunkO, chunkT):
+			writeLines(&buf, chunkO, false)
+		default:
+			clean = false
+			buf.WriteString(_CONFLICT_START)
+			writeLines(&buf, chunkO, true)
+			buf.WriteString(_CONFLICT_SEP)
+			writeLines(&buf, chunkT, true)
+			buf.WriteString(_CONFLICT_END)
+		}
+
+		if j == len(b) {
+			break
+		}
+		buf.WriteString(b[j])
+		i, x, y = j+1, endX+1, endY+1
+	}
+
+	return buf.String(), clean
+}
+
+// matchLines returns the index of the line into b matched by every line of a,
+// or -1 if it is not matched, according to the longest common subsequence.
+func matchLines(a, b []string) []int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			match[i] = j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
+
+// splitLines splits s after of every new line.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// equalLines reports whether both lists of lines are equal.
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeLines writes the lines into buf, ending the last one with a new line if
+// endLine is true.
+func writeLines(buf *bytes.Buffer, lines []string, endLine bool) {
+	for _, v := range lines {
+		buf.WriteString(v)
+	}
+	if endLine && len(lines) != 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		buf.WriteByte('\n')
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		msg                string
+		base, ours, theirs string
+		merged             string
+		clean              bool
+	}{
+		{
+			"no changes",
+			"a\nb\nc\n", "a\nb\nc\n", "a\nb\nc\n",
+			"a\nb\nc\n", true,
+		},
+		{
+			"edit only in ours",
+			"a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\n",
+			"a\nB\nc\n", true,
+		},
+		{
+			"edit only in theirs",
+			"a\nb\nc\n", "a\nb\nc\n", "a\nB\nc\n",
+			"a\nB\nc\n", true,
+		},
+		{
+			"edits in different lines",
+			"a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n",
+			"A\nb\nc\nd\nE\n", true,
+		},
+		{
+			"same edit in both",
+			"a\nb\nc\n", "a\nB\nc\n", "a\nB\nc\n",
+			"a\nB\nc\n", true,
+		},
+		{
+			"conflicting edits",
+			"a\nb\nc\n", "a\nX\nc\n", "a\nY\nc\n",
+			"a\n" + _CONFLICT_START + "X\n" + _CONFLICT_SEP + "Y\n" + _CONFLICT_END + "c\n", false,
+		},
+		{
+			"edit and removal",
+			"a\nb\nc\n", "a\nX\nc\n", "a\nc\n",
+			"a\n" + _CONFLICT_START + "X\n" + _CONFLICT_SEP + _CONFLICT_END + "c\n", false,
+		},
+		{
+			"insert at start",
+			"a\nb\n", "a\nb\n", "x\na\nb\n",
+			"x\na\nb\n", true,
+		},
+		{
+			"insert at end",
+			"a\nb\n", "a\nb\nx\n", "a\nb\n",
+			"a\nb\nx\n", true,
+		},
+		{
+			"inserts at start and end",
+			"a\nb\n", "x\na\nb\n", "a\nb\ny\n",
+			"x\na\nb\ny\n", true,
+		},
+		{
+			"conflicting inserts at end",
+			"a\n", "a\nx\n", "a\ny\n",
+			"a\n" + _CONFLICT_START + "x\n" + _CONFLICT_SEP + "y\n" + _CONFLICT_END, false,
+		},
+		{
+			"empty base, new file",
+			"", "", "a\nb\n",
+			"a\nb\n", true,
+		},
+		{
+			"empty base, same files",
+			"", "a\nb\n", "a\nb\n",
+			"a\nb\n", true,
+		},
+		{
+			"empty base, different files",
+			"", "a\n", "b\n",
+			_CONFLICT_START + "a\n" + _CONFLICT_SEP + "b\n" + _CONFLICT_END, false,
+		},
+		{
+			"no trailing newline, edit in theirs",
+			"a\nb", "a\nb", "a\nB",
+			"a\nB", true,
+		},
+		{
+			"no trailing newline, edit in ours",
+			"a\nb\nc", "A\nb\nc", "a\nb\nC",
+			"A\nb\nC", true,
+		},
+		{
+			"trailing newline added in theirs",
+			"a\nb", "a\nb", "a\nb\n",
+			"a\nb\n", true,
+		},
+		{
+			"conflict without trailing newline",
+			"a\nb", "a\nX", "a\nY",
+			"a\n" + _CONFLICT_START + "X\n" + _CONFLICT_SEP + "Y\n" + _CONFLICT_END, false,
+		},
+	}
+
+	for _, tt := range tests {
+		merged, clean := merge3(tt.base, tt.ours, tt.theirs)
+
+		if merged != tt.merged || clean != tt.clean {
+			t.Errorf("%s: merge3(%q, %q, %q) = %q, %v; want %q, %v", tt.msg,
+				tt.base, tt.ours, tt.theirs, merged, clean, tt.merged, tt.clean)
+		}
+	}
+}
+
+func TestMatchLines(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		match []int
+	}{
+		{"", "", []int{}},
+		{"a\n", "", []int{-1}},
+		{"", "a\n", []int{}},
+		{"a\nb\nc\n", "a\nb\nc\n", []int{0, 1, 2}},
+		{"a\nb\nc\n", "x\na\nb\nc\n", []int{1, 2, 3}},
+		{"a\nb\nc\n", "a\nc\n", []int{0, -1, 1}},
+		{"a\nb\nc\n", "a\nX\nc\ny\n", []int{0, -1, 2}},
+		{"a\nb", "a\nb\n", []int{0, -1}},
+	}
+
+	for _, tt := range tests {
+		match := matchLines(splitLines(tt.a), splitLines(tt.b))
+
+		if !reflect.DeepEqual(match, tt.match) {
+			t.Errorf("matchLines(%q, %q) = %v, want %v", tt.a, tt.b, match, tt.match)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "foo")
+
+	p, err := New(testConf("lib"), &Options{Dir: dir, Output: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The project is not changed with the same templates.
+	user := &Conf{Email: testConf("").Email}
+	updated, conflicts, err := Update(dir, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 0 || len(conflicts) != 0 {
+		t.Errorf("Update: got updated %v, conflicts %v; want none", updated, conflicts)
+	}
+
+	// Another template pack.
+	packDir := filepath.Join(tmp, "pack")
+	writeFiles(t, packDir, map[string]string{
+		_PACK_MANIFEST: "name: mini\nfiles:\n  - path: go.mod\n    template: GoMod\n",
+	})
+	user.Pack = packDir
+
+	if _, _, err = Update(dir, user); err == nil || !strings.Contains(err.Error(), "template pack") {
+		t.Errorf("Update with another pack: got error %v, want one about the template pack", err)
+	}
+}
+
+func TestIgnoreMeta(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "foo")
+
+	cfg := testConf("lib")
+	cfg.VCS = "git"
+
+	p, err := New(cfg, &Options{Dir: dir, Output: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, ignored := range map[string]bool{
+		_META_FILE:                     false,
+		path.Join(_BASE_DIR, "go.mod"): true,
+	} {
+		cmd := exec.Command("git", "check-ignore", "-q", name)
+		cmd.Dir = dir
+		if err = cmd.Run(); (err == nil) != ignored {
+			t.Errorf("%s: got ignored %v, want %v", name, err == nil, ignored)
+		}
+	}
+}
@@ -8,53 +8,119 @@ package wizard
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
-// copyFile copies a file from source to destination.
-func copyFile(destination, source string) error {
-	src, err := ioutil.ReadFile(source)
+// createFile creates a file.
+func createFile(dst string) (*os.File, error) {
+	file, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("copy error reading: %s", err)
+		return nil, fmt.Errorf("file error: %s", err)
+	}
+	if err = file.Chmod(_FILE_PERM); err != nil {
+		return nil, fmt.Errorf("file error: %s", err)
 	}
 
-	err = ioutil.WriteFile(destination, src, _FILE_PERM)
+	return file, nil
+}
+
+// copyFile copies the file "src" to "dst", with the same permissions.
+func (p *Project) copyFile(dst, src string) error {
+	info, err := os.Stat(src)
 	if err != nil {
-		return fmt.Errorf("copy error writing: %s", err)
+		return fmt.Errorf("copy error: %s", err)
+	}
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("copy error reading: %s", err)
 	}
 
+	if err = p.writeFile(dst, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("copy error writing: %s", err)
+	}
 	return nil
 }
 
-// createFile creates a file.
-func createFile(dst string) (*os.File, error) {
-	file, err := os.Create(dst)
+// mkdir creates the directory name, which must not exist.
+func (p *Project) mkdir(name string) error {
+	exist, err := p.fs.Exists(name)
 	if err != nil {
-		return nil, fmt.Errorf("file error: %s", err)
+		return err
 	}
-	if err = file.Chmod(_FILE_PERM); err != nil {
-		return nil, fmt.Errorf("file error: %s", err)
+	if exist {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
 	}
+	return p.fs.MkdirAll(name, _DIR_PERM)
+}
 
-	return file, nil
+// mkdirAll creates the directory name, along with any necessary parents.
+func (p *Project) mkdirAll(name string) error {
+	return p.fs.MkdirAll(name, _DIR_PERM)
 }
 
-// getProjectName returns the project name from Readme file.
-// It should be in the first line.
-func getProjectName() (string, error) {
-	info, err := os.Stat(_README)
-	if os.IsNotExist(err) {
-		info, err = os.Stat(filepath.Join("..", _README))
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("file %s not found", _README)
+// writeFile writes data to the file name, creating it with permissions perm.
+func (p *Project) writeFile(name string, data []byte, perm os.FileMode) error {
+	if p.written != nil {
+		p.written[name] = data
+	}
+	return p.fs.WriteFile(name, data, perm)
+}
+
+// runCmd runs the command args into the directory dir, adding env to the
+// environment. Out of the file system of the OS, it is only added to the list
+// of commands not run.
+func (p *Project) runCmd(ctx context.Context, dir string, env []string, args ...string) error {
+	line := make([]string, len(args))
+	for i, v := range args {
+		if strings.ContainsAny(v, " \t\"'") {
+			v = strconv.Quote(v)
+		}
+		line[i] = v
+	}
+	run := strings.Join(line, " ")
+
+	// The commands can only be run in the file system of the OS.
+	if _, ok := p.fs.(OSFS); !ok {
+		p.cmds = append(p.cmds, run)
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	if len(env) != 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("command %q failed: %s\n%s", run, err, out)
+	}
+	if out != nil {
+		out_ := string(out)
+		if wd, err := os.Getwd(); err == nil {
+			out_ = strings.Replace(out_, wd+string(os.PathSeparator), "", 1)
 		}
+
+		fmt.Fprint(p.out, out_)
 	}
+	return nil
+}
 
-	file, err := os.Open(info.Name())
+// getProjectName returns the project name from the Readme file into the
+// directory dir. It should be in the first line.
+func getProjectName(dir string) (string, error) {
+	file, err := os.Open(filepath.Join(dir, _README))
 	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("file %s not found", _README)
+		}
 		return "", err
 	}
 	defer file.Close()
@@ -65,5 +131,5 @@ func getProjectName() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return string(line), nil
+	return strings.TrimSpace(string(line)), nil
 }
@@ -8,12 +8,19 @@
 package wizard
 
 import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
 	"fmt"
-	"go/build"
+	"io"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
 	"text/template"
 )
@@ -23,11 +30,11 @@ const (
 	_DIR_PERM  = 0755
 	_FILE_PERM = 0644
 
-	_COMMENT_CHAR = "//" // For comments in source code files
-	_HEADER_CHAR  = "="  // Header under the project name
+	_HEADER_CHAR = "=" // Header under the project name
 
-	// Subdirectory where is installed through "go get"
-	_DATA_PATH = "github.com/tredoe/wizard/data"
+	_DATA_DIR = "data" // directory with the license texts
+
+	_COMMIT_MSG = "Initial commit" // message of the initial commit, by default
 
 	_README      = "README.md"
 	_USER_CONFIG = ".gowizard" // Configuration file per user
@@ -44,147 +51,596 @@ var (
 		"none": "none",
 	}
 
-	/*// VCS configuration files
+	// VCS configuration files, into the repository
 	listConfigVCS = map[string]string{
 		"bzr": ".bzr/branch/branch.conf",
 		"git": ".git/config",
 		"hg":  ".hg/hgrc",
-	}*/
+	}
+
+	// VCS configuration files per user, into the home directory, by order of
+	// preference. The ones into ".config" are got from $XDG_CONFIG_HOME, if
+	// it is set.
+	listUserConfigVCS = map[string][]string{
+		"bzr": {".config/breezy/breezy.conf", ".bazaar/bazaar.conf"},
+		"git": {".gitconfig", ".config/git/config"},
+		"hg":  {".hgrc", ".config/hg/hgrc"},
+	}
 )
 
-// Available licenses
+// Go version used when it is not set in the configuration, and the Go release
+// in use can not be got.
+const _GO_VERSION = "1.21"
+
 var (
-	ListLicenseSorted = []string{"AGPL", "Apache", "CC0", "GPL", "MPL", "none"}
-
-	ListLicense = map[string]string{
-		"AGPL":   "GNU Affero General Public License, version 3 or later",
-		"Apache": "Apache License, version 2.0",
-		"CC0":    "Creative Commons CC0, version 1.0 Universal",
-		"GPL":    "GNU General Public License, version 3 or later",
-		"MPL":    "Mozilla Public License, version 2.0",
-		"none":   "proprietary license",
-	}
-	ListLowerLicense = map[string]string{
-		"agpl":   "AGPL",
-		"apache": "Apache",
-		"cc0":    "CC0",
-		"gpl":    "GPL",
-		"mpl":    "MPL",
-		"none":   "none",
+	// Versions in the directives "go" and "toolchain" of go.mod.
+	reGoVersion = regexp.MustCompile(`^1\.[0-9]+(\.[0-9]+)?$`)
+	reToolchain = regexp.MustCompile(`^(go1\.[0-9]+(\.[0-9]+)?\S*|default)$`)
+
+	reRelease = regexp.MustCompile(`^go(1\.[0-9]+)`)
+
+	// Characters not allowed in the name of environment variables.
+	reNotEnv = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// goVersion returns the language version of the Go release in use, i.e. "1.21".
+func goVersion() string {
+	if m := reRelease.FindStringSubmatch(runtime.Version()); m != nil {
+		return m[1]
 	}
+	return _GO_VERSION
+}
+
+// Kinds of project layout
+var (
+	ListKindSorted = []string{"cmd", "lib", "libcmd", "svc"}
+
+	ListKind = map[string]string{
+		"cmd":    "command",
+		"lib":    "library",
+		"libcmd": "library with command in directory cmd",
+		"svc":    "web service",
+	}
+)
+
+// Kinds of file to add to an existing project
+var (
+	ListAddSorted = []string{"example", "file", "pkg", "test"}
+
+	ListAdd = map[string]string{
+		"example": "example file",
+		"file":    "source file",
+		"pkg":     "package into a new directory",
+		"test":    "test file",
+	}
+)
+
+// Modes to use the SPDX license identifier in the license header.
+const (
+	SPDXOnly = "only" // instead of the license text
+	SPDXAdd  = "add"  // after the license text
 )
 
-// project represents all information to create a project.
-type project struct {
-	dataDir string             // directory with templates
+// Texts of the licenses, embedded to be available in the installed binary.
+//
+//go:embed data/*.txt
+var dataFS embed.FS
+
+// Project represents all information to create a project.
+type Project struct {
+	dataDir string             // directory with custom data, if any
+	tmplDir string             // directory with custom templates, if any
+	packDir string             // directory of the template pack, if any
+	pack    *Pack              // template pack to create the project
+	dir     string             // directory where the project is created, if any
+	fs      FS                 // file system where the project is created
+	out     io.Writer          // output of the commands run
+	cmds    []string           // commands not run, out of the file system of the OS
+	year    int                // year of the copyright; the actual one if it is 0
+	written map[string][]byte  // files written at creating the project
 	tmpl    *template.Template // set of templates
-	cfg     *Conf
+	cfg     *Conf              // configuration given by the user
+	data    *Data              // data passed to templates
 }
 
-// NewProject initializes information for a new project.
-func NewProject(cfg *Conf) (*project, error) {
-	// To get the path of the templates directory.
-	pkg, err := build.Import(_DATA_PATH, build.Default.GOPATH, build.FindOnly)
+// Options represents the options to create a project.
+type Options struct {
+	// FS is the file system where the project is created. By default, it is
+	// the one of the operating system.
+	FS FS
+
+	// Output is where the output of the commands run is written. By default,
+	// it is the standard output.
+	Output io.Writer
+
+	// Dir is the directory where the project is created, which can be an
+	// existing one if it is empty, excepting the metadata of a VCS (i.e. a
+	// repository just cloned), or the current one ("."). By default, it is
+	// the program name into the current directory.
+	Dir string
+}
+
+// New initializes information for a new project, with the options given, if
+// any.
+//
+// The data is got from the directory in cfg.DataDir, if any, falling back to
+// the embedded one for the files not found there. The project is created from
+// the template pack into the directory cfg.Pack, if any, or else from the
+// default one.
+func New(cfg *Conf, opts *Options) (*Project, error) {
+	if err := cfg.checkLicense(); err != nil {
+		return nil, fmt.Errorf("New: %s", err)
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = "lib"
+	}
+	if err := cfg.checkKind(); err != nil {
+		return nil, fmt.Errorf("New: %s", err)
+	}
+	for _, dir := range []string{cfg.DataDir, cfg.TemplateDir, cfg.Pack} {
+		if dir == "" {
+			continue
+		}
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("New: directory not found: %s", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("New: expected directory: %s", dir)
+		}
+	}
+
+	pack, err := cfg.loadPack()
 	if err != nil {
-		return nil, fmt.Errorf("NewProject: data directory not found: %s", err)
+		return nil, fmt.Errorf("New: %s", err)
 	}
 
-	return &project{pkg.Dir, new(template.Template), cfg}, nil
+	p := &Project{
+		dataDir: cfg.DataDir,
+		tmplDir: cfg.TemplateDir,
+		packDir: cfg.Pack,
+		pack:    pack,
+		fs:      OSFS{},
+		out:     os.Stdout,
+		tmpl:    new(template.Template),
+		cfg:     cfg,
+		data:    &Data{Conf: cfg},
+	}
+	if opts != nil {
+		if opts.FS != nil {
+			p.fs = opts.FS
+		}
+		if opts.Output != nil {
+			p.out = opts.Output
+		}
+		p.dir = opts.Dir
+	}
+	return p, nil
+}
+
+// NewProject initializes information for a new project, with the options by
+// default.
+func NewProject(cfg *Conf) (*Project, error) {
+	return New(cfg, nil)
 }
 
-// Create creates a new project.
-func (p *project) Create() (err error) {
-	dirs := []string{
-		p.cfg.Program,
-		filepath.Join(p.cfg.Program, "doc"),
-		filepath.Join(p.cfg.Program, "testdata"),
+// SetFS sets the file system where the project is created. By default, it is
+// the one of the operating system.
+func (p *Project) SetFS(fs FS) {
+	p.fs = fs
+}
+
+// StepError represents an error at a step of the creation of a project.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return "create failed at " + e.Step + ": " + e.Err.Error()
+}
+
+// Create creates a new project, executing the template pack.
+//
+// In the file system of the OS, the project is created into a staging
+// directory which is moved into place on success, and removed on any error.
+func (p *Project) Create() error {
+	return p.CreateContext(context.Background())
+}
+
+// CreateContext is like Create but it stops at the first step after of ctx is
+// done, killing the command run, if any.
+func (p *Project) CreateContext(ctx context.Context) (err error) {
+	dir := p.targetDir()
+
+	exist, err := p.checkDir(dir)
+	if err != nil {
+		return &StepError{"directory", err}
 	}
-	for _, v := range dirs {
-		if err = os.Mkdir(v, _DIR_PERM); err != nil {
-			return fmt.Errorf("directory error: %s", err)
+
+	root := dir
+	_, inOS := p.fs.(OSFS)
+
+	if inOS {
+		var parents []string // directories created, to remove on error
+
+		defer func() {
+			if err != nil {
+				for i := len(parents) - 1; i >= 0; i-- {
+					os.Remove(parents[i])
+				}
+			}
+		}()
+
+		// The staging directory is created into the target one when it
+		// exists, since its parent could not be writable.
+		if exist {
+			root, err = ioutil.TempDir(dir, ".gowizard-")
+		} else {
+			parents = missingDirs(filepath.Dir(dir))
+			if err = os.MkdirAll(filepath.Dir(dir), _DIR_PERM); err == nil {
+				root, err = ioutil.TempDir(filepath.Dir(dir), "."+filepath.Base(dir)+"-")
+			}
+		}
+		if err != nil {
+			return &StepError{"directory", err}
+		}
+		root = filepath.Clean(root)
+		defer os.RemoveAll(root)
+
+		if err = os.Chmod(root, _DIR_PERM); err != nil {
+			return &StepError{"directory", err}
+		}
+	} else {
+		root = p.fsRoot()
+		if err = p.mkdir(root); err != nil {
+			return &StepError{"directory", err}
 		}
 	}
 
-	p.parseLicense(_COMMENT_CHAR)
+	p.parseLicense()
 	p.parseProject()
 
+	var userFiles []string
+	if p.tmplDir != "" {
+		if userFiles, err = p.parseTemplateDir(); err != nil {
+			return &StepError{"user templates", err}
+		}
+	}
+
+	// Render data
+
+	p.data.Email = ""
+	if p.cfg.Email != "" {
+		p.data.Email = fmt.Sprintf("%s <%s>",
+			p.cfg.Author, strings.Replace(p.cfg.Email, "@", " AT ", -1))
+	}
+	p.data.ProjectHeader = strings.Repeat(_HEADER_CHAR, len(p.cfg.Project))
+
+	p.data.ImportPath = ""
 	if len(p.cfg.ImportPaths) != 0 {
-		p.cfg.ImportPath = path.Join(p.cfg.ImportPaths[0], p.cfg.Program)
+		p.data.ImportPath = path.Join(p.cfg.ImportPaths[0], p.cfg.Program)
+	}
+	p.data.EnvPrefix = strings.ToUpper(reNotEnv.ReplaceAllString(p.cfg.Program, "_"))
+	p.data.ModulePath = p.data.ImportPath
+	if p.data.ModulePath == "" {
+		p.data.ModulePath = p.cfg.Program
+	}
+	if p.cfg.GoVersion == "" {
+		p.cfg.GoVersion = goVersion()
+	}
+	if err = p.cfg.checkGoMod(); err != nil {
+		return &StepError{"configuration", err}
+	}
+	if err = p.setVars(); err != nil {
+		return &StepError{"configuration", err}
+	}
+
+	steps := []struct {
+		name string
+		fn   func(dir string) error
+	}{
+		{"pack files", p.createFromPack},
+		{"license files", p.copyLicense},
+		{"user templates", func(dir string) error {
+			return p.addFromTemplateDir(dir, userFiles)
+		}},
 	}
 
-	// Render project files
+	p.written = make(map[string][]byte)
+	defer func() { p.written = nil }()
 
-	err = p.parseFromVar(filepath.Join(p.cfg.Program, p.cfg.Program)+".go", "Go")
-	if err != nil {
-		return err
+	for _, step := range steps {
+		if err = ctx.Err(); err == nil {
+			err = step.fn(root)
+		}
+		if err != nil {
+			return &StepError{step.name, err}
+		}
 	}
-	err = p.parseFromVar(filepath.Join(p.cfg.Program, "_"+p.cfg.Program)+"_test.go", "Test")
-	if err != nil {
-		return err
+	if err = p.writeMeta(root); err != nil {
+		return &StepError{"metadata", err}
 	}
-	err = p.parseFromVar(filepath.Join(p.cfg.Program, "_example_test.go"), "Example")
+	files, err := p.writtenFiles(root)
 	if err != nil {
-		return err
+		return &StepError{"metadata", err}
 	}
 
-	// Add license file
+	// Move into place
+
+	if inOS {
+		var moved []string // paths to remove on error
+
+		defer func() {
+			if err != nil {
+				for _, v := range moved {
+					os.RemoveAll(v)
+				}
+			}
+		}()
+
+		if !exist {
+			if err = os.Rename(root, dir); err != nil {
+				return &StepError{"moving into place", err}
+			}
+			moved = append(moved, dir)
+		} else {
+			files, err := ioutil.ReadDir(root)
+			if err != nil {
+				return &StepError{"moving into place", err}
+			}
+			for _, f := range files {
+				name := filepath.Join(dir, f.Name())
+
+				if err = os.Rename(filepath.Join(root, f.Name()), name); err != nil {
+					return &StepError{"moving into place", err}
+				}
+				moved = append(moved, name)
+			}
+		}
+	}
 
-	if p.cfg.License != "none" {
-		license := ListLowerLicense[p.cfg.License]
-		licenseDst := filepath.Join(p.cfg.Program, "LICENSE-"+license+".txt")
+	// Run commands, as the initialization of the VCS
 
-		err = copyFile(licenseDst, filepath.Join(p.dataDir, license+".txt"))
+	if err = ctx.Err(); err == nil {
+		err = p.runPost(ctx, dir)
+	}
+	if err != nil {
+		return &StepError{"pack commands", err}
+	}
+
+	if p.cfg.Commit {
+		if err = ctx.Err(); err == nil {
+			err = p.commit(ctx, dir, files)
+		}
 		if err != nil {
+			return &StepError{"initial commit", err}
+		}
+	}
+	return nil
+}
+
+// commit stages the files, relative to the directory dir, and creates the
+// initial commit into the repository of the VCS, with the author of the
+// configuration as author and committer. The files are given explicitly, since
+// the ignore file could match some of them.
+func (p *Project) commit(ctx context.Context, dir string, files []string) error {
+	msg := p.cfg.CommitMsg
+	if msg == "" {
+		msg = _COMMIT_MSG
+	}
+
+	addr := p.cfg.Author
+	if p.cfg.Email != "" {
+		addr = fmt.Sprintf("%s <%s>", p.cfg.Author, p.cfg.Email)
+	}
+
+	var env []string
+	var cmds [][]string
+
+	switch p.cfg.VCS {
+	case "bzr":
+		if addr != "" {
+			env = []string{"BZR_EMAIL=" + addr, "BRZ_EMAIL=" + addr}
+		}
+		cmds = [][]string{
+			append([]string{"bzr", "add", "-q", "--"}, files...),
+			{"bzr", "commit", "-q", "-m", msg},
+		}
+	case "git":
+		if p.cfg.Author != "" {
+			env = append(env, "GIT_AUTHOR_NAME="+p.cfg.Author,
+				"GIT_COMMITTER_NAME="+p.cfg.Author)
+		}
+		if p.cfg.Email != "" {
+			env = append(env, "GIT_AUTHOR_EMAIL="+p.cfg.Email,
+				"GIT_COMMITTER_EMAIL="+p.cfg.Email)
+		}
+		cmds = [][]string{
+			append([]string{"git", "add", "-f", "--"}, files...),
+			{"git", "commit", "-q", "-m", msg},
+		}
+	case "hg":
+		if addr != "" {
+			env = []string{"HGUSER=" + addr}
+		}
+		cmds = [][]string{
+			append([]string{"hg", "add", "-q", "--"}, files...),
+			{"hg", "commit", "-m", msg},
+		}
+	default:
+		return nil
+	}
+
+	for _, args := range cmds {
+		if err := p.runCmd(ctx, dir, env, args...); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	// Render common files
+// writtenFiles returns the files written into the directory dir at creating the
+// project, including the metadata, relative to that directory and sorted.
+func (p *Project) writtenFiles(dir string) ([]string, error) {
+	files := []string{_META_FILE}
 
-	err = p.parseFromVar(filepath.Join(p.cfg.Program, _README), "Readme")
-	if err != nil {
-		return err
+	for name := range p.written {
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, rel)
 	}
-	err = p.parseFromVar(filepath.Join(p.cfg.Program, "CONTRIBUTORS.txt.md"), "Contributors")
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// targetDir returns the directory where the project is created.
+func (p *Project) targetDir() string {
+	if p.dir != "" {
+		return p.dir
+	}
+	return p.cfg.Program
+}
+
+// missingDirs returns the directory dir and its parents which do not exist,
+// from the top one.
+func missingDirs(dir string) []string {
+	dirs := make([]string, 0)
+
+	for {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			break
+		}
+		dirs = append([]string{dir}, dirs...)
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return dirs
+}
+
+// fsRoot returns the directory where the project is rendered when the file
+// system is not the one of the operating system, as an archive: the last
+// element of the target directory, so the paths are not absolute nor out of
+// the root.
+func (p *Project) fsRoot() string {
+	dir, err := filepath.Abs(p.targetDir())
 	if err != nil {
-		return err
+		dir = p.targetDir()
 	}
-	err = p.parseFromVar(filepath.Join(dirs[1], "_changelog.txt.md"), "Changelog")
+	return filepath.Base(dir)
+}
+
+// checkDir checks that the directory dir does not exist, or that it is empty
+// excepting the metadata of a VCS; and reports whether it exists.
+func (p *Project) checkDir(dir string) (bool, error) {
+	exist, err := p.fs.Exists(dir)
+	if err != nil || !exist {
+		return false, err
+	}
+	if _, ok := p.fs.(OSFS); !ok {
+		return true, &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+	}
+
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return err
+		return true, err
 	}
+	for _, f := range files {
+		name := f.Name()
 
-	// The file AUTHORS is for copyright holders.
-	if p.cfg.License != "cc0" {
-		err = p.parseFromVar(filepath.Join(p.cfg.Program, "AUTHORS.txt.md"), "Authors")
-		if err != nil {
-			return err
+		if _, ok := ListVCS[name[1:]]; !ok || name[0] != '.' || !f.IsDir() {
+			return true, &os.PathError{Op: "mkdir", Path: dir,
+				Err: errors.New("directory not empty")}
 		}
 	}
+	return true, nil
+}
 
-	// == VCS
+// copyLicense copies the texts of the licenses into the directory dir, if any,
+// plus the ones required by them.
+func (p *Project) copyLicense(dir string) error {
+	list := p.cfg.allLicenses()
 
-	if p.cfg.VCS != "none" {
-		ignoreFile := "." + p.cfg.VCS + "ignore"
-		if err = p.parseFromVar(filepath.Join(p.cfg.Program, ignoreFile),
-			"Ignore"); err != nil {
-			return err
+	for i := 0; i < len(list); i++ {
+		for _, id := range list[i].Requires {
+			l, ok := LookupLicense(id)
+			if !ok {
+				return fmt.Errorf("copy error: license %q required by %q not found",
+					id, list[i].ID)
+			}
+
+			added := false
+			for _, v := range list {
+				if v == l {
+					added = true
+					break
+				}
+			}
+			if !added {
+				list = append(list, l)
+			}
 		}
+	}
 
-		// Initialize VCS
-		out, err := exec.Command(p.cfg.VCS, "init", p.cfg.Program).CombinedOutput()
+	for _, license := range list {
+		src, err := p.licenseText(license)
 		if err != nil {
-			return err
+			return fmt.Errorf("copy error reading: %s", err)
 		}
-		if out != nil {
-			out_ := string(out)
-			if wd, err := os.Getwd(); err == nil {
-				out_ = strings.Replace(out_, wd+string(os.PathSeparator), "", 1)
+
+		if license.Notice {
+			tmpl, err := p.tmpl.New("LicenseText").Parse(string(src))
+			if err != nil {
+				return fmt.Errorf("parsing error: %s", err)
 			}
 
-			fmt.Print(out_)
+			var buf bytes.Buffer
+			if err = tmpl.Execute(&buf, p.data); err != nil {
+				return fmt.Errorf("execution failed: %s", err)
+			}
+			src = buf.Bytes()
+		}
+
+		err = p.writeFile(filepath.Join(dir, license.File()), src, _FILE_PERM)
+		if err != nil {
+			return fmt.Errorf("copy error writing: %s", err)
 		}
 	}
 
 	return nil
 }
+
+// needAuthors reports whether some license needs the file AUTHORS.
+func (p *Project) needAuthors() bool {
+	code, _ := p.cfg.licenses()
+	doc, _ := p.cfg.docLicenses()
+
+	for _, v := range append(code, doc...) {
+		if v.Authors {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseText returns the full text of the license. The file in the custom
+// data directory has preference over the text of the license registered.
+func (p *Project) licenseText(license *License) ([]byte, error) {
+	name := license.ID + ".txt"
+
+	if p.dataDir != "" {
+		src, err := ioutil.ReadFile(filepath.Join(p.dataDir, name))
+		if !os.IsNotExist(err) {
+			return src, err
+		}
+	}
+	return license.text()
+}
@@ -0,0 +1,285 @@
+// Copyright 2010 Jonas mg
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package wizard
+
+import (
+	"archive/zip"
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// goVet runs "go vet" on all the packages into the directory dir.
+func goVet(t *testing.T, dir string) {
+	cmd := exec.Command("go", "vet", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=", "GOWORK=off")
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go vet into %s: %s\n%s", dir, err, out)
+	}
+}
+
+// testConf returns a configuration to create projects in the tests.
+func testConf(kind string) *Conf {
+	return &Conf{
+		Project:     "Foo",
+		Program:     "foo",
+		License:     "mpl",
+		Author:      "Jane Doe",
+		Email:       "jane@example.com",
+		VCS:         "none",
+		Kind:        kind,
+		ImportPaths: []string{"example.com/jane"},
+	}
+}
+
+func TestCreateKinds(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go tool not found")
+	}
+
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, kind := range ListKindSorted {
+		dir := filepath.Join(tmp, kind)
+
+		p, err := New(testConf(kind), &Options{Dir: dir, Output: ioutil.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = p.Create(); err != nil {
+			t.Errorf("kind %s: %s", kind, err)
+			continue
+		}
+		goVet(t, dir)
+	}
+}
+
+func TestCmdFormat(t *testing.T) {
+	fs := NewMemFS()
+	p, err := New(testConf("cmd"), &Options{FS: fs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"main.go", "doc.go"} {
+		src, err := fs.ReadFile(path.Join(p.targetDir(), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := format.Source(src)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if !bytes.Equal(out, src) {
+			t.Errorf("%s is not formatted:\n%s", name, src)
+		}
+	}
+}
+
+func TestIgnoreHg(t *testing.T) {
+	cfg := testConf("lib")
+	cfg.VCS = "hg"
+
+	// The header of Mercurial is added once per project.
+	for i := 0; i < 2; i++ {
+		fs := NewMemFS()
+		p, err := New(cfg, &Options{FS: fs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = p.Create(); err != nil {
+			t.Fatal(err)
+		}
+
+		src, err := fs.ReadFile(path.Join(p.targetDir(), ".hgignore"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(src), hgIgnoreTop); n != 1 {
+			t.Errorf("project %d: got %d headers of Mercurial, want 1", i+1, n)
+		}
+	}
+}
+
+func TestArchiveRoot(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	fs := NewZipFS(&buf)
+	p, err := New(testConf("lib"), &Options{Dir: dir, FS: fs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range r.File {
+		if !strings.HasPrefix(f.Name, "foo/") {
+			t.Errorf("archive: got path %q, want it into %q", f.Name, "foo/")
+		}
+	}
+
+	// The tree printed at the dry run.
+	if p, err = New(testConf("lib"), &Options{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = p.DryRun(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "foo/\n  ") {
+		t.Errorf("DryRun: got tree\n%s", buf.String())
+	}
+}
+
+func TestCreateError(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "a", "b", "foo")
+
+	// The web service needs a newer release of Go.
+	cfg := testConf("svc")
+	cfg.GoVersion = "1.15"
+
+	p, err := New(cfg, &Options{Dir: dir, Output: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err == nil {
+		t.Fatal("expected error at creating the project")
+	}
+
+	// The parent directories created are removed.
+	if _, err = os.Stat(filepath.Join(tmp, "a")); !os.IsNotExist(err) {
+		t.Errorf("got parent directory, or error %v", err)
+	}
+}
+
+func TestCreateCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "foo")
+
+	cfg := testConf("libcmd")
+	cfg.VCS = "git"
+	cfg.Commit = true
+
+	p, err := New(cfg, &Options{Dir: dir, Output: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// All files created, excepting the base of the metadata, are committed.
+	var want []string
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if base := info.Name(); base == ".git" || base == _BASE_DIR {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, name)
+		want = append(want, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("git", "ls-files")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Fields(string(out))
+
+	sort.Strings(want)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files committed:\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestRequiredLicense(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "wizard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "foo")
+
+	cfg := testConf("lib")
+	cfg.License = "lgpl"
+
+	p, err := New(cfg, &Options{Dir: dir, Output: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The LGPL refers to the text of the GPL.
+	for _, id := range []string{"lgpl", "gpl"} {
+		if _, err = os.Stat(filepath.Join(dir, licenseFile(t, id))); err != nil {
+			t.Error(err)
+		}
+	}
+
+	license, _, err := projectLicenses(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if license != "lgpl" {
+		t.Errorf("projectLicenses = %q, want %q", license, "lgpl")
+	}
+}